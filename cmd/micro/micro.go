@@ -337,6 +337,8 @@ func main() {
 
 	action.InitTabs(b)
 	action.InitGlobals()
+	action.InitDebug()
+	defer action.CloseDebug()
 
 	err = config.RunPluginFn("init")
 	if err != nil {
@@ -777,6 +777,7 @@ func (h *BufPane) saveBufToFile(filename string, action string, callback func())
 					h.Buf.Path = filename
 					h.Buf.SetName(filename)
 					InfoBar.Message("Saved " + filename)
+					h.syncBreakpoints()
 					if callback != nil {
 						callback()
 					}
@@ -800,6 +801,7 @@ func (h *BufPane) saveBufToFile(filename string, action string, callback func())
 		h.Buf.Path = filename
 		h.Buf.SetName(filename)
 		InfoBar.Message("Saved " + filename)
+		h.syncBreakpoints()
 		if callback != nil {
 			callback()
 		}
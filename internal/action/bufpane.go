@@ -387,6 +387,8 @@ func (h *BufPane) HandleEvent(event tcell.Event) {
 				// }
 				if h.Cursor.HasSelection() {
 					h.Cursor.CopySelection(clipboard.PrimaryReg)
+				} else {
+					h.debugClick(e)
 				}
 				h.mouseReleased = true
 			}
@@ -688,6 +690,10 @@ var BufKeyActions = map[string]BufKeyAction{
 	"JumpLine":                  (*BufPane).JumpLine,
 	"Deselect":                  (*BufPane).Deselect,
 	"ClearInfo":                 (*BufPane).ClearInfo,
+	"ToggleBreakpoint":          (*BufPane).ToggleBreakpoint,
+	"AddWatch":                  (*BufPane).AddWatch,
+	"EvalUnderCursor":           (*BufPane).EvalUnderCursor,
+	"RunToCursor":               (*BufPane).RunToCursor,
 	"None":                      (*BufPane).None,
 
 	// This was changed to InsertNewline but I don't want to break backwards compatibility
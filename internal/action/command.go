@@ -64,7 +64,8 @@ func InitCommands() {
 		"raw":        {(*BufPane).RawCmd, nil},
 		"textfilter": {(*BufPane).TextFilterCmd, nil},
 		"exec":       {(*BufPane).ExecCmd, compgen},
-		"php":        {(*BufPane).PhpCmd, nil},
+		"php":        {(*BufPane).PhpCmd, PhpComplete},
+		"debug":      {(*BufPane).DebugCmd, DebugComplete},
 	}
 }
 
@@ -0,0 +1,201 @@
+package action
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+const consolePaneName = "Console"
+
+// consoleEntry is an evaluated expression of the console
+type consoleEntry struct {
+	expr  string
+	value *debugger.Property
+	err   string
+}
+
+// consoleEntries holds the console transcript by session ID
+var consoleEntries = make(map[int][]*consoleEntry)
+
+// consoleSession is the session shown in the console, the current session
+// or the last one evaluated in
+var consoleSession int
+
+// consoleTree is the tree shown in the console pane, consoleExprs maps
+// its lines to the entries
+var consoleTree propertyTree
+var consoleExprs []*consoleEntry
+
+func currentSession() int {
+	if dbg != nil {
+		for _, s := range dbg.Sessions() {
+			if s.Current {
+				return s.ID
+			}
+		}
+	}
+	return 0
+}
+
+func renderConsole() []string {
+	consoleTree = propertyTree{}
+	consoleExprs = nil
+
+	if id := currentSession(); id != 0 {
+		consoleSession = id
+	}
+
+	add := func(s string, item treeItem, e *consoleEntry) {
+		consoleTree.add(s, item)
+		consoleExprs = append(consoleExprs, e)
+	}
+
+	for _, e := range consoleEntries[consoleSession] {
+		for i, l := range strings.Split(e.expr, "\n") {
+			prompt := "> "
+			if i > 0 {
+				prompt = ". "
+			}
+			add(prompt+l, treeItem{}, e)
+		}
+
+		switch {
+		case e.err != "":
+			add("  error: "+e.err, treeItem{}, e)
+		case e.value == nil:
+			add("  ...", treeItem{}, e)
+		default:
+			consoleTree.addValue("", e.value)
+		}
+		for len(consoleExprs) < len(consoleTree.lines) {
+			consoleExprs = append(consoleExprs, e)
+		}
+	}
+
+	add(fmt.Sprintf("i: eval in session %d, Enter: expand or edit", consoleSession), treeItem{}, nil)
+	return consoleTree.lines
+}
+
+// openConsole opens the eval console of the debugger
+func (h *BufPane) openConsole() *debugPane {
+	return h.openDebugPane(&debugPane{
+		name:   consolePaneName,
+		render: renderConsole,
+		activate: func(y int) {
+			if y >= 0 && y < len(consoleTree.items) && consoleTree.items[y].prop != nil {
+				consoleTree.activate(dbg, y)
+				return
+			}
+			expr := ""
+			if y >= 0 && y < len(consoleExprs) && consoleExprs[y] != nil {
+				expr = consoleExprs[y].expr
+			}
+			promptEval(expr)
+		},
+		keys: map[rune]func(y int){
+			'i': func(int) { promptEval("") },
+		},
+	}, false)
+}
+
+// promptEval reads an expression in the infobar and evaluates it. Lines
+// ending with \ or with unclosed brackets are continued on the next prompt.
+func promptEval(msg string) {
+	var lines []string
+	var prompt func(msg string)
+	prompt = func(msg string) {
+		p := "eval> "
+		if len(lines) > 0 {
+			p = "....> "
+		}
+		ptype := "PhpEval:" + strconv.Itoa(currentSession())
+		InfoBar.Prompt(p, msg, ptype, nil, func(resp string, canceled bool) {
+			if canceled {
+				return
+			}
+			if strings.HasSuffix(resp, "\\") {
+				lines = append(lines, strings.TrimSuffix(resp, "\\"))
+				prompt("")
+				return
+			}
+			lines = append(lines, resp)
+			expr := strings.Join(lines, "\n")
+			if unclosed(expr) {
+				prompt("")
+				return
+			}
+			if strings.TrimSpace(expr) != "" {
+				consoleEval(expr)
+			}
+		})
+	}
+	prompt(msg)
+}
+
+// unclosed returns true if the expression has unclosed brackets outside of
+// string literals
+func unclosed(expr string) bool {
+	depth := 0
+	var quote rune
+	escaped := false
+	for _, r := range expr {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if r == '\\' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(' || r == '[' || r == '{':
+			depth++
+		case r == ')' || r == ']' || r == '}':
+			depth--
+		}
+	}
+	return depth > 0 || quote != 0
+}
+
+// consoleEval evaluates the expression and adds it to the console of the
+// current session
+func consoleEval(expr string) {
+	id := currentSession()
+	e := &consoleEntry{expr: expr}
+	err := dbg.Eval(expr, func(p *debugger.Property, err error) {
+		if err != nil {
+			e.err = err.Error()
+		} else {
+			e.value = p
+		}
+		refreshDebugPane(consolePaneName)
+	})
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	consoleEntries[id] = append(consoleEntries[id], e)
+	consoleSession = id
+
+	if p := findDebugPane(consolePaneName); p != nil {
+		p.refresh()
+		p.Cursor.GotoLoc(p.Buf.End())
+		p.Relocate()
+	}
+}
+
+// consoleCmd evaluates the expression in the console, without arguments it
+// opens the console and prompts for an expression
+func (h *BufPane) consoleCmd(args []string) {
+	h.openConsole()
+	if len(args) == 0 {
+		promptEval("")
+		return
+	}
+	consoleEval(strings.Join(args, " "))
+}
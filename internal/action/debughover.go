@@ -0,0 +1,120 @@
+package action
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell"
+)
+
+// wordAt returns the word at loc as the {w} substitution of ExecCmd
+// detects it
+func (h *BufPane) wordAt(loc buffer.Loc) string {
+	c := buffer.NewCursor(h.Buf, loc)
+	c.SelectWord()
+	return strings.TrimSpace(string(c.GetSelection()))
+}
+
+// objectPathRegexp matches the $object-> or Class:: chain before a member name
+var objectPathRegexp = regexp.MustCompile(`\$?\w+((->|::)\$?\w+)*(->|::)\$?$`)
+
+// debugExprAt returns the PHP expression at loc: the word at loc with the
+// $ of a variable and the object or class it is a member of
+func (h *BufPane) debugExprAt(loc buffer.Loc) string {
+	line := []rune(string(h.Buf.LineBytes(loc.Y)))
+	if loc.X >= len(line) || !util.IsWordChar(line[loc.X]) {
+		return ""
+	}
+
+	start := loc.X
+	for start > 0 && util.IsWordChar(line[start-1]) {
+		start--
+	}
+
+	expr := h.wordAt(loc)
+	prefix := string(line[:start])
+	if m := objectPathRegexp.FindString(prefix); m != "" {
+		expr = m + expr
+	} else if strings.HasSuffix(prefix, "$") {
+		expr = "$" + expr
+	}
+	return expr
+}
+
+// debugExprUnderCursor returns the selection or the expression at the cursor
+func (h *BufPane) debugExprUnderCursor() string {
+	if h.Cursor.HasSelection() {
+		return strings.TrimSpace(string(h.Cursor.GetSelection()))
+	}
+	return h.debugExprAt(h.Cursor.Loc)
+}
+
+// describeValue returns the expression with the type and the value
+func describeValue(expr string, p *debugger.Property) string {
+	return expr + " = " + p.Summary()
+}
+
+// EvalUnderCursor shows the value of the selection or the variable under
+// the cursor while the debugger is stopped. Expressions other than
+// variables are evaluated, they can run code of the script.
+func (h *BufPane) EvalUnderCursor() bool {
+	expr := h.debugExprUnderCursor()
+	if expr == "" {
+		return false
+	}
+
+	show := func(p *debugger.Property, err error) {
+		if err != nil {
+			InfoBar.Error(expr, ": ", err)
+			return
+		}
+		InfoBar.Message(describeValue(expr, p))
+	}
+	err := h.debugger().Inspect(expr, show)
+	if errors.Is(err, debugger.ErrNotVariable) {
+		err = h.debugger().Eval(expr, show)
+	}
+	if err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	return true
+}
+
+// clickExpr is the expression last clicked, results of earlier clicks are
+// not shown
+var clickExpr string
+
+// debugClick shows the value of the variable clicked with the mouse. The
+// terminal reports no mouse motion without a pressed button, so the value
+// is shown when a click without selection is released.
+func (h *BufPane) debugClick(e *tcell.EventMouse) {
+	if dbg == nil || !config.GetGlobalOption("debughover").(bool) {
+		return
+	}
+
+	mx, my := e.Position()
+	v := h.GetView()
+	if mx < v.X || mx >= v.X+v.Width || my < v.Y || my >= v.Y+v.Height {
+		return
+	}
+
+	expr := h.debugExprAt(h.LocFromVisual(buffer.Loc{X: mx, Y: my}))
+	clickExpr = expr
+	if expr == "" {
+		return
+	}
+
+	// only variables are read, errors are not shown, e.g. when clicking a
+	// function name
+	dbg.Inspect(expr, func(p *debugger.Property, err error) {
+		if err == nil && expr == clickExpr {
+			InfoBar.Message(describeValue(expr, p))
+		}
+	})
+}
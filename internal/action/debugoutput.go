@@ -0,0 +1,103 @@
+package action
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+)
+
+// debugOutputs holds the script output buffers by session ID. They stay
+// after the session ends.
+var debugOutputs = make(map[int]*buffer.Buffer)
+
+func (e debugEditor) Output(session int, text string) {
+	b, ok := debugOutputs[session]
+	if !ok {
+		b = buffer.NewBufferFromString("", "", buffer.BTLog)
+		b.SetName(fmt.Sprintf("Output #%d", session))
+		debugOutputs[session] = b
+
+		for _, s := range dbg.Sessions() {
+			if s.ID != session {
+				continue
+			}
+			b.SetName(fmt.Sprintf("Output #%d %s", session, path.Base(s.File)))
+			// output of the current session is shown right away
+			if s.Current {
+				showDebugOutput(b, false)
+			}
+		}
+	}
+
+	b.EventHandler.Insert(b.End(), text)
+	for _, p := range outputPanes(b) {
+		scrollToEnd(p)
+	}
+}
+
+// outputPanes returns the panes showing the buffer
+func outputPanes(b *buffer.Buffer) []*BufPane {
+	var panes []*BufPane
+	for _, t := range Tabs.List {
+		for _, p := range t.Panes {
+			if bp, ok := p.(*BufPane); ok && bp.Buf == b {
+				panes = append(panes, bp)
+			}
+		}
+	}
+	return panes
+}
+
+func scrollToEnd(p *BufPane) {
+	p.CursorEnd()
+	v := p.GetView()
+	endY := p.Buf.End().Y
+	if endY > v.StartLine+v.Height {
+		v.StartLine = endY - v.Height + 2
+		p.SetView(v)
+	}
+}
+
+// showDebugOutput opens the output buffer in a split below the current
+// pane unless it is already shown. The current pane stays active unless
+// focus is set.
+func showDebugOutput(b *buffer.Buffer, focus bool) {
+	if len(outputPanes(b)) > 0 {
+		return
+	}
+	h := MainTab().CurPane()
+	if h == nil {
+		return
+	}
+	active := MainTab().active
+	scrollToEnd(h.HSplitBuf(b))
+	if !focus {
+		MainTab().SetActive(active)
+	}
+}
+
+// outputCmd opens the output pane of the current or the given session
+func (h *BufPane) outputCmd(args []string) error {
+	id := 0
+	if len(args) > 0 {
+		var err error
+		if id, err = strconv.Atoi(args[0]); err != nil {
+			return fmt.Errorf("invalid session %q", args[0])
+		}
+	} else {
+		for _, s := range dbg.Sessions() {
+			if s.Current {
+				id = s.ID
+			}
+		}
+	}
+
+	b, ok := debugOutputs[id]
+	if !ok {
+		return fmt.Errorf("no output of session %d", id)
+	}
+	showDebugOutput(b, true)
+	return nil
+}
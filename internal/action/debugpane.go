@@ -0,0 +1,151 @@
+package action
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell"
+)
+
+// debugPane is a read-only pane showing debugger state like variables or
+// the call stack. Its text is produced by render and Enter calls activate
+// with the line under the cursor. If decorate is set it is called with each
+// rendered buffer, e.g. to add gutter messages. keys are additional actions
+// on the line under the cursor and Tab calls toggle. If filter is set,
+// other typed runes edit a live filter like in qfixPane.
+type debugPane struct {
+	*BufPane
+	name     string
+	render   func() []string
+	activate func(y int)
+	decorate func(b *buffer.Buffer)
+	keys     map[rune]func(y int)
+	toggle   func(y int)
+	filter   func(f string)
+
+	filterText string
+}
+
+// openDebugPane shows the debug pane with the name of p. If the pane is not
+// open yet p is placed in a split next to h.
+func (h *BufPane) openDebugPane(p *debugPane, vertical bool) *debugPane {
+	if open := findDebugPane(p.name); open != nil {
+		open.refresh()
+		return open
+	}
+
+	p.BufPane = NewBufPaneFromBuf(p.newBuffer(), h.tab)
+
+	if vertical {
+		p.splitID = MainTab().GetNode(h.splitID).VSplit(h.Buf.Settings["splitright"].(bool))
+	} else {
+		p.splitID = MainTab().GetNode(h.splitID).HSplit(h.Buf.Settings["splitbottom"].(bool))
+	}
+	MainTab().Panes = append(MainTab().Panes, p)
+	MainTab().Resize()
+	MainTab().SetActive(len(MainTab().Panes) - 1)
+
+	return p
+}
+
+func (h *debugPane) newBuffer() *buffer.Buffer {
+	b := buffer.NewBufferFromString(strings.Join(h.render(), "\n"), "", buffer.BTLog)
+	b.SetName(h.name)
+	if h.decorate != nil {
+		h.decorate(b)
+	}
+	return b
+}
+
+// findDebugPane returns the open debug pane with the given name or nil
+func findDebugPane(name string) *debugPane {
+	for _, t := range Tabs.List {
+		for _, p := range t.Panes {
+			if pane, ok := p.(*debugPane); ok && pane.name == name {
+				return pane
+			}
+		}
+	}
+	return nil
+}
+
+// refreshDebugPane re-renders the debug pane with the given name if it is open
+func refreshDebugPane(name string) {
+	if p := findDebugPane(name); p != nil {
+		p.refresh()
+	}
+}
+
+// refresh re-renders the pane text keeping the cursor line and the view
+func (h *debugPane) refresh() {
+	y := h.Cursor.Y
+	v := h.GetView()
+	startLine := v.StartLine
+
+	b := h.newBuffer()
+	h.OpenBuffer(b)
+
+	h.Cursor.GotoLoc(buffer.Loc{X: 0, Y: util.Clamp(y, 0, b.LinesNum()-1)})
+	v = h.GetView()
+	v.StartLine = util.Clamp(startLine, 0, b.LinesNum()-1)
+	h.SetView(v)
+	h.Relocate()
+}
+
+func (h *debugPane) HandleEvent(event tcell.Event) {
+	if e, ok := event.(*tcell.EventKey); ok {
+		switch e.Key() {
+		case tcell.KeyEnter:
+			if h.activate != nil {
+				h.activate(h.Cursor.Y)
+			}
+			return
+		case tcell.KeyEsc:
+			h.Quit()
+			return
+		case tcell.KeyTab:
+			if h.toggle != nil {
+				h.toggle(h.Cursor.Y)
+				return
+			}
+		case tcell.KeyRune:
+			if f, ok := h.keys[e.Rune()]; ok && e.Modifiers() == 0 {
+				f(h.Cursor.Y)
+				return
+			}
+			if h.filter != nil {
+				h.setFilter(h.filterText + string(e.Rune()))
+				return
+			}
+		case tcell.KeyDEL:
+			if h.filter != nil && h.filterText != "" {
+				h.setFilter(h.filterText[:len(h.filterText)-1])
+				return
+			}
+		}
+	}
+
+	h.BufPane.HandleEvent(event)
+}
+
+// setFilter re-renders the pane with the filter
+func (h *debugPane) setFilter(f string) {
+	h.filterText = f
+	InfoBar.Message("filter: " + f)
+	h.filter(f)
+	h.refresh()
+}
+
+// openSourceLine opens the local file at the 1-based line in the pane the
+// debugger opens files in
+func openSourceLine(fname string, line int) {
+	if _, err := os.Stat(fname); err != nil {
+		InfoBar.Error("no source file ", fname)
+		return
+	}
+	editor.OpenCmd([]string{fname})
+	editor.GotoCmd([]string{strconv.Itoa(line)})
+}
@@ -0,0 +1,189 @@
+package action
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/xdebug"
+)
+
+const profilePaneName = "Profile"
+
+// profile is the loaded Xdebug profile, profileSort the column its
+// functions are sorted by
+var profile *xdebug.Profile
+var profileSort = 0
+
+// profileHeader is the number of lines before the functions in the pane
+const profileHeader = 2
+
+// profileColumns are the titles of the sortable columns of the pane
+var profileColumns = []string{"Incl ms", "Self ms", "Incl mem", "Self mem", "Calls", "Function"}
+
+func renderProfile() []string {
+	if profile == nil {
+		return []string{"no profile"}
+	}
+
+	wd, _ := filepath.Abs(".")
+	ti, mi := profile.Event("Time"), profile.Event("Memory")
+
+	lines := []string{
+		fmt.Sprintf("%s  1-6: sort by column, Enter: open function", profile.Cmd),
+		fmt.Sprintf("%10s %10s %10s %10s %7s  %s", profileColumns[0], profileColumns[1], profileColumns[2], profileColumns[3], profileColumns[4], profileColumns[5]),
+	}
+	for _, f := range profile.Functions {
+		where := f.File
+		if rel, err := filepath.Rel(wd, where); err == nil && !strings.HasPrefix(rel, "..") {
+			where = rel
+		}
+		lines = append(lines, fmt.Sprintf("%10s %10s %10s %10s %7d  %s %s:%d",
+			profileTime(f.Inclusive, ti), profileTime(f.Self, ti),
+			profileMemory(f.Inclusive, mi), profileMemory(f.Self, mi),
+			f.Calls, f.Name, where, f.Line))
+	}
+	return lines
+}
+
+// profileTime formats the time event of the costs in milliseconds
+func profileTime(costs []int64, event int) string {
+	if event < 0 {
+		return "-"
+	}
+	d := profile.TimeUnit() * time.Duration(costs[event])
+	return fmt.Sprintf("%.2f", float64(d.Nanoseconds())/1e6)
+}
+
+// profileMemory formats the memory event of the costs, which is negative
+// if the function freed memory
+func profileMemory(costs []int64, event int) string {
+	if event < 0 {
+		return "-"
+	}
+	if n := costs[event]; n < 0 {
+		return "-" + humanize.Bytes(uint64(-n))
+	}
+	return humanize.Bytes(uint64(costs[event]))
+}
+
+// sortProfile sorts the functions of the profile by the column, numbers
+// descending and names ascending
+func sortProfile(column int) {
+	profileSort = column
+	ti, mi := profile.Event("Time"), profile.Event("Memory")
+	cost := func(costs []int64, event int) int64 {
+		if event < 0 {
+			return 0
+		}
+		return costs[event]
+	}
+
+	profile.Sort(func(a, b *xdebug.FunctionCost) bool {
+		switch column {
+		case 0:
+			return cost(a.Inclusive, ti) > cost(b.Inclusive, ti)
+		case 1:
+			return cost(a.Self, ti) > cost(b.Self, ti)
+		case 2:
+			return cost(a.Inclusive, mi) > cost(b.Inclusive, mi)
+		case 3:
+			return cost(a.Self, mi) > cost(b.Self, mi)
+		case 4:
+			return a.Calls > b.Calls
+		}
+		return a.Name < b.Name
+	})
+	refreshDebugPane(profilePaneName)
+}
+
+// setLineCosts shows the share of time of the profiled lines in the gutter
+// of their buffers
+func setLineCosts() {
+	buffer.LineCosts = nil
+	if profile == nil {
+		return
+	}
+	event := profile.Event("Time")
+	if event < 0 || profile.Totals[event] <= 0 {
+		return
+	}
+
+	buffer.LineCosts = make(map[string]map[int]string)
+	for fname, lines := range profile.Lines {
+		costs := make(map[int]string)
+		for l, c := range lines {
+			if c[event] > 0 {
+				costs[l-1] = fmt.Sprintf("%.1f%%", 100*float64(c[event])/float64(profile.Totals[event]))
+			}
+		}
+		buffer.LineCosts[fname] = costs
+	}
+}
+
+// openProfile shows the functions of the profile. Enter opens the source
+// of the function and the digit keys sort by the columns.
+func (h *BufPane) openProfile() {
+	keys := make(map[rune]func(y int))
+	for i := range profileColumns {
+		column := i
+		keys[rune('1'+i)] = func(int) { sortProfile(column) }
+	}
+
+	h.openDebugPane(&debugPane{
+		name:   profilePaneName,
+		render: renderProfile,
+		activate: func(y int) {
+			i := y - profileHeader
+			if profile == nil || i < 0 || i >= len(profile.Functions) {
+				return
+			}
+			f := profile.Functions[i]
+			openSourceLine(f.File, f.Line)
+		},
+		keys: keys,
+	}, false)
+}
+
+// profileCmd loads a profile written by the Xdebug profiler and shows it:
+//
+//	profile [FILE]
+//	profile clear
+//
+// Without FILE the newest cachegrind.out.* of the project root or the
+// temporary directory is loaded.
+func (h *BufPane) profileCmd(args []string) error {
+	if len(args) > 0 && args[0] == "clear" {
+		profile = nil
+		setLineCosts()
+		if p := findDebugPane(profilePaneName); p != nil {
+			p.Quit()
+		}
+		return nil
+	}
+
+	var fname string
+	if len(args) > 0 {
+		fname = args[0]
+	} else {
+		fname = xdebug.FindProfile(debugRoot, os.TempDir())
+		if fname == "" {
+			return fmt.Errorf("no cachegrind.out.* profile in %s or %s", debugRoot, os.TempDir())
+		}
+	}
+
+	p, err := h.xdebugClient().LoadProfile(fname)
+	if err != nil {
+		return err
+	}
+	profile = p
+	sortProfile(profileSort)
+	setLineCosts()
+	h.openProfile()
+	InfoBar.Message("Profile ", fname)
+	return nil
+}
@@ -0,0 +1,63 @@
+package action
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+const sessionsPaneName = "Sessions"
+
+func renderSessions() []string {
+	if dbg == nil || len(dbg.Sessions()) == 0 {
+		return []string{"no sessions"}
+	}
+
+	var lines []string
+	for _, s := range dbg.Sessions() {
+		mark := " "
+		if s.Current {
+			mark = ">"
+		}
+		lines = append(lines, fmt.Sprintf("%s %2d %-8s %-10s %-10s %s", mark, s.ID, s.Status, s.IDEKey, s.Language, path.Base(s.File)))
+	}
+	return lines
+}
+
+// sessionAt returns the ID of the session on line y of the sessions pane
+func sessionAt(y int) (int, bool) {
+	if dbg == nil {
+		return 0, false
+	}
+	sessions := dbg.Sessions()
+	if y < 0 || y >= len(sessions) {
+		return 0, false
+	}
+	return sessions[y].ID, true
+}
+
+// openSessions shows the session picker. Enter selects the session, d
+// detaches it and x stops it. The backend is looked up when a key is
+// pressed, it changes with the debugbackend option.
+func (h *BufPane) openSessions() {
+	onSession := func(f func(b debugger.Backend, id int) error) func(y int) {
+		return func(y int) {
+			if id, ok := sessionAt(y); ok {
+				if err := f(dbg, id); err != nil {
+					InfoBar.Error(err)
+				}
+			}
+		}
+	}
+
+	h.openDebugPane(&debugPane{
+		name:     sessionsPaneName,
+		render:   renderSessions,
+		activate: onSession(debugger.Backend.SelectSession),
+		keys: map[rune]func(y int){
+			'd': onSession(debugger.Backend.DetachSession),
+			'x': onSession(debugger.Backend.StopSession),
+		},
+	}, false)
+}
@@ -0,0 +1,28 @@
+package action
+
+import (
+	"strconv"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+)
+
+// OpenSource opens engine source without local file, e.g. eval()'d code,
+// in a read-only buffer. Its path is the engine URI so that the debugger
+// line is marked in it. A modified buffer of the pane is kept, the source
+// is opened in a split then.
+func (e debugEditor) OpenSource(uri, src string, line int) {
+	h := e.BufPane
+	if h.Buf.AbsPath != uri || string(h.Buf.Bytes()) != src {
+		b := buffer.NewBufferFromString(src, "", buffer.BTSource)
+		b.AbsPath = uri
+		b.SetName(uri)
+		b.SetOptionNative("filetype", "php")
+
+		if h.Buf.Modified() {
+			h = h.HSplitBuf(b)
+		} else {
+			h.OpenBuffer(b)
+		}
+	}
+	h.GotoCmd([]string{strconv.Itoa(line)})
+}
@@ -0,0 +1,45 @@
+package action
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const stackPaneName = "Stack"
+
+func renderStack() []string {
+	if dbg == nil || len(dbg.Stack()) == 0 {
+		return []string{"no stack"}
+	}
+
+	wd, _ := filepath.Abs(".")
+
+	var lines []string
+	for i, f := range dbg.Stack() {
+		mark := " "
+		if i == dbg.Depth() {
+			mark = ">"
+		}
+		fname, err := dbg.LocalPath(f.File)
+		if err != nil {
+			fname = f.File
+		} else if rel, err := filepath.Rel(wd, fname); err == nil && !strings.HasPrefix(rel, "..") {
+			fname = rel
+		}
+		lines = append(lines, fmt.Sprintf("%s %2d %s:%d %s", mark, f.Level, fname, f.Line, f.Where))
+	}
+	return lines
+}
+
+func (h *BufPane) openStack() {
+	h.openDebugPane(&debugPane{
+		name:   stackPaneName,
+		render: renderStack,
+		activate: func(y int) {
+			if err := dbg.SelectFrame(y); err != nil {
+				InfoBar.Error(err)
+			}
+		},
+	}, false)
+}
@@ -0,0 +1,148 @@
+package action
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/zyedidia/micro/v2/internal/xdebug"
+)
+
+const tracePaneName = "Trace"
+
+// trace is the loaded function trace, traceFilter the filter of function
+// names typed in its pane
+var trace *xdebug.Trace
+var traceFilter string
+
+// traceCalls maps lines of the trace pane to the calls
+var traceCalls []*xdebug.TraceCall
+
+func renderTrace() []string {
+	traceCalls = nil
+	if trace == nil {
+		return []string{"no trace"}
+	}
+
+	wd, _ := filepath.Abs(".")
+	filter := strings.ToLower(traceFilter)
+
+	// matches returns true if the call or one of its callees matches the filter
+	var matches func(c *xdebug.TraceCall) bool
+	matches = func(c *xdebug.TraceCall) bool {
+		if strings.Contains(strings.ToLower(c.Name), filter) {
+			return true
+		}
+		for _, child := range c.Children {
+			if matches(child) {
+				return true
+			}
+		}
+		return false
+	}
+
+	lines := []string{fmt.Sprintf("%s  Tab: fold, Enter: go to call site, type to filter", trace.Start)}
+	traceCalls = append(traceCalls, nil)
+
+	var add func(calls []*xdebug.TraceCall, indent string)
+	add = func(calls []*xdebug.TraceCall, indent string) {
+		for _, c := range calls {
+			if filter != "" && !matches(c) {
+				continue
+			}
+
+			mark := " "
+			expanded := c.Expanded || filter != ""
+			if len(c.Children) > 0 {
+				mark = "+"
+				if expanded {
+					mark = "-"
+				}
+			}
+			where := c.File
+			if rel, err := filepath.Rel(wd, where); err == nil && !strings.HasPrefix(rel, "..") {
+				where = rel
+			}
+			lines = append(lines, fmt.Sprintf("%10.6f %10.3fms %9s  %s%s %s  %s:%d",
+				c.EntryTime, c.Duration()*1000, memoryDelta(c.MemoryDelta()),
+				indent, mark, c.String(), where, c.Line))
+			traceCalls = append(traceCalls, c)
+
+			if expanded {
+				add(c.Children, indent+"  ")
+			}
+		}
+	}
+	add(trace.Calls, "")
+	return lines
+}
+
+// memoryDelta formats the change of memory usage with its sign
+func memoryDelta(n int64) string {
+	if n < 0 {
+		return "-" + humanize.Bytes(uint64(-n))
+	}
+	return "+" + humanize.Bytes(uint64(n))
+}
+
+// traceCallAt returns the call on line y of the trace pane or nil
+func traceCallAt(y int) *xdebug.TraceCall {
+	if y < 0 || y >= len(traceCalls) {
+		return nil
+	}
+	return traceCalls[y]
+}
+
+// openTrace shows the call tree of the trace. Tab folds and unfolds the
+// callees, Enter opens the call site and typed runes filter function names.
+func (h *BufPane) openTrace() {
+	h.openDebugPane(&debugPane{
+		name:   tracePaneName,
+		render: renderTrace,
+		activate: func(y int) {
+			if c := traceCallAt(y); c != nil {
+				openSourceLine(c.File, c.Line)
+			}
+		},
+		toggle: func(y int) {
+			if c := traceCallAt(y); c != nil && len(c.Children) > 0 {
+				c.Expanded = !c.Expanded
+				refreshDebugPane(tracePaneName)
+			}
+		},
+		filter: func(f string) { traceFilter = f },
+	}, false)
+}
+
+// traceCmd loads a function trace written by Xdebug and shows it:
+//
+//	trace [FILE]
+//
+// Without FILE the newest trace.*.xt of the project root or the temporary
+// directory is loaded.
+func (h *BufPane) traceCmd(args []string) error {
+	var fname string
+	if len(args) > 0 {
+		fname = args[0]
+	} else {
+		fname = xdebug.FindTrace(debugRoot, os.TempDir())
+		if fname == "" {
+			return fmt.Errorf("no trace.*.xt trace in %s or %s", debugRoot, os.TempDir())
+		}
+	}
+
+	t, err := h.xdebugClient().LoadTrace(fname)
+	if err != nil {
+		return err
+	}
+	trace = t
+	traceFilter = ""
+	if p := findDebugPane(tracePaneName); p != nil {
+		p.filterText = ""
+	}
+	h.openTrace()
+	InfoBar.Message("Trace ", fname)
+	return nil
+}
@@ -0,0 +1,171 @@
+package action
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// treeItem is the variable context or property shown on a line of a
+// variables tree. If more is set the line loads more children of prop.
+type treeItem struct {
+	ctx  *debugger.Context
+	prop *debugger.Property
+	more bool
+}
+
+// propertyTree renders properties and their expanded children
+type propertyTree struct {
+	lines []string
+	items []treeItem
+}
+
+func (t *propertyTree) add(s string, item treeItem) {
+	t.lines = append(t.lines, s)
+	t.items = append(t.items, item)
+}
+
+func (t *propertyTree) addProperties(props []*debugger.Property, depth int) {
+	pad := strings.Repeat("  ", depth)
+	for _, p := range props {
+		sign := " "
+		if p.NumChildren > 0 {
+			sign = "+"
+			if p.Expanded {
+				sign = "-"
+			}
+		}
+		t.add(fmt.Sprintf("%s%s %s: %s", pad, sign, p.Name, p.Summary()), treeItem{prop: p})
+
+		if !p.Expanded {
+			continue
+		}
+		t.addProperties(p.Children, depth+1)
+		if p.HasMore() {
+			more := fmt.Sprintf("%s    ... %d more", pad, p.NumChildren-len(p.Children))
+			t.add(more, treeItem{prop: p, more: true})
+		}
+	}
+}
+
+// addValue adds the line of a value with the label, e.g. of an evaluated
+// expression, followed by its expanded children
+func (t *propertyTree) addValue(label string, p *debugger.Property) {
+	sign := " "
+	if p.NumChildren > 0 {
+		sign = "+"
+		if p.Expanded {
+			sign = "-"
+		}
+	}
+	t.add(fmt.Sprintf("%s %s%s", sign, label, p.Summary()), treeItem{prop: p})
+	if !p.Expanded {
+		return
+	}
+	t.addProperties(p.Children, 1)
+	if p.HasMore() {
+		t.add(fmt.Sprintf("      ... %d more", p.NumChildren-len(p.Children)), treeItem{prop: p, more: true})
+	}
+}
+
+// activate expands or collapses the item on line y
+func (t *propertyTree) activate(b debugger.Backend, y int) {
+	if y < 0 || y >= len(t.items) {
+		return
+	}
+	switch item := t.items[y]; {
+	case item.ctx != nil:
+		b.ToggleContext(item.ctx)
+	case item.more:
+		b.LoadMore(item.prop)
+	case item.prop != nil && item.prop.NumChildren > 0:
+		b.ToggleProperty(item.prop)
+	}
+}
+
+const varsPaneName = "Variables"
+
+// varsTree is the tree shown in the variables pane
+var varsTree propertyTree
+
+func renderVariables() []string {
+	varsTree = propertyTree{}
+	if dbg == nil || len(dbg.Contexts()) == 0 {
+		varsTree.add("no variables", treeItem{})
+		return varsTree.lines
+	}
+
+	for _, c := range dbg.Contexts() {
+		sign := "+"
+		if c.Expanded {
+			sign = "-"
+		}
+		varsTree.add(sign+" "+c.Name, treeItem{ctx: c})
+		if c.Expanded {
+			varsTree.addProperties(c.Properties, 1)
+		}
+	}
+	return varsTree.lines
+}
+
+// openVariables opens the variables pane of the debugger
+func (h *BufPane) openVariables() {
+	h.openDebugPane(&debugPane{
+		name:     varsPaneName,
+		render:   renderVariables,
+		activate: func(y int) { varsTree.activate(dbg, y) },
+		keys: map[rune]func(y int){
+			's': func(y int) {
+				if y >= 0 && y < len(varsTree.items) && varsTree.items[y].prop != nil && !varsTree.items[y].more {
+					promptSetVariable(varsTree.items[y].prop)
+				}
+			},
+		},
+	}, true)
+}
+
+// promptSetVariable reads the new value of the property in the infobar.
+// Scalars are edited as values of their type, other values as PHP
+// expressions.
+func promptSetVariable(p *debugger.Property) {
+	a := debugger.AssignProperty(p, "")
+	prompt := fmt.Sprintf("set %s = ", p.FullName)
+	value := ""
+	if a.Type != "" {
+		prompt = fmt.Sprintf("set %s (%s) = ", p.FullName, a.Type)
+		value = p.Value
+	}
+	InfoBar.Prompt(prompt, value, "PhpSet", nil, func(resp string, canceled bool) {
+		if canceled {
+			return
+		}
+		setVariable(debugger.AssignProperty(p, resp))
+	})
+}
+
+// setVariable sets the variable and reports the result in the infobar
+func setVariable(a debugger.Assignment) {
+	err := dbg.SetVariable(a, func(err error) {
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		InfoBar.Message(a.Name, " set")
+	})
+	if err != nil {
+		InfoBar.Error(err)
+	}
+}
+
+// setCmd sets a variable in the selected stack frame:
+//
+//	set [-c CONTEXT] [-d DEPTH] [-t TYPE] NAME [=] VALUE...
+func (h *BufPane) setCmd(args []string) error {
+	a, err := debugger.ParseAssignment(args, h.debugger().Depth())
+	if err != nil {
+		return err
+	}
+	setVariable(a)
+	return nil
+}
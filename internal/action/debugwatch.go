@@ -0,0 +1,120 @@
+package action
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+const watchPaneName = "Watches"
+
+// watchTree is the tree shown in the watches pane
+var watchTree propertyTree
+
+// changedWatches holds lines of the watches pane with changed values
+var changedWatches []int
+
+func renderWatches() []string {
+	watchTree = propertyTree{}
+	changedWatches = nil
+
+	if dbg == nil || len(dbg.Watches()) == 0 {
+		watchTree.add("no watches, use: php watch add EXPRESSION", treeItem{})
+		return watchTree.lines
+	}
+
+	for i, w := range dbg.Watches() {
+		if w.Changed {
+			changedWatches = append(changedWatches, len(watchTree.lines))
+		}
+		switch {
+		case w.Error != "":
+			watchTree.add(fmt.Sprintf("  %d %s: error: %s", i+1, w.Expression, w.Error), treeItem{})
+		case w.Value == nil:
+			watchTree.add(fmt.Sprintf("  %d %s", i+1, w.Expression), treeItem{})
+		default:
+			watchTree.addValue(fmt.Sprintf("%d %s: ", i+1, w.Expression), w.Value)
+		}
+	}
+	return watchTree.lines
+}
+
+func decorateWatches(b *buffer.Buffer) {
+	for _, y := range changedWatches {
+		b.AddMessage(buffer.NewMessageAtLine("watch", "value changed", y+1, buffer.MTWarning))
+	}
+}
+
+func (h *BufPane) openWatches() {
+	h.openDebugPane(&debugPane{
+		name:     watchPaneName,
+		render:   renderWatches,
+		activate: func(y int) { watchTree.activate(dbg, y) },
+		decorate: decorateWatches,
+	}, false)
+}
+
+// saveWatches writes watch expressions to the project debug directory
+func saveWatches() {
+	if err := debugger.SaveWatches(debugRoot, dbg.WatchExpressions()); err != nil {
+		log.Println(err)
+		InfoBar.Error(err)
+	}
+}
+
+// watchCmd lists, adds and removes watch expressions:
+//
+//	watch
+//	watch add EXPRESSION...
+//	watch remove N
+func (h *BufPane) watchCmd(args []string) error {
+	b := h.debugger()
+
+	if len(args) == 0 {
+		h.openWatches()
+		return nil
+	}
+
+	switch args[0] {
+	case "add":
+		expr := strings.TrimSpace(strings.Join(args[1:], " "))
+		if expr == "" {
+			return fmt.Errorf("usage: php watch add EXPRESSION")
+		}
+		b.AddWatch(expr)
+	case "remove":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: php watch remove N")
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return err
+		}
+		if err := b.RemoveWatch(n); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown watch command %q", args[0])
+	}
+
+	saveWatches()
+	return nil
+}
+
+// AddWatch adds the selection or the word under the cursor to the debugger
+// watch expressions
+func (h *BufPane) AddWatch() bool {
+	expr := h.debugExprUnderCursor()
+	if expr == "" {
+		return false
+	}
+
+	h.debugger().AddWatch(expr)
+	saveWatches()
+	InfoBar.Message("Watch added: ", expr)
+	return true
+}
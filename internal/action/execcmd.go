@@ -87,9 +87,7 @@ func (h *BufPane) ExecCmd(args []string) {
 			sel = string(h.Cursor.GetSelection())
 			sel = strings.TrimSpace(sel)
 		} else {
-			h.Cursor.SelectWord()
-			word = strings.TrimSpace(string(h.Cursor.GetSelection()))
-			h.Cursor.Deselect(true)
+			word = h.wordAt(h.Cursor.Loc)
 			h.Cursor.SelectLine()
 			currline = strings.TrimSpace(string(h.Cursor.GetSelection()))
 			h.Cursor.Deselect(true)
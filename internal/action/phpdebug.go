@@ -1,28 +1,464 @@
 package action
 
 import (
+	"bytes"
+	"errors"
 	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/dap"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/util"
 	"github.com/zyedidia/micro/v2/internal/xdebug"
 )
 
-var xc *xdebug.Client
+// dbg is the backend of the last debug or php command, xc and dc are the
+// backends created so far
+var (
+	dbg debugger.Backend
+	xc  *xdebug.Client
+	dc  *dap.Client
+)
 
-func (h *BufPane) PhpCmd(args []string) {
+// editor opens files for the debugger backends
+var editor debugger.Editor
+
+// sessionIDs numbers the sessions of both backends, the output buffers are
+// kept by session ID
+var sessionIDs debugger.SessionCounter
+
+// debugRoot is the project root where debugger files are stored
+var debugRoot string
+
+// syncedBreakpoints holds breakpoint lines of files as they were last saved
+// and passed to the debugger
+var syncedBreakpoints = make(map[string][]int)
+
+// InitDebug loads breakpoints of the project in the current directory
+// and registers the $(debug) statusline function
+func InitDebug() {
+	display.SetStatusInfoFn("debug", debugStatus)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	debugRoot = debugger.ProjectRoot(wd)
+
+	bps, err := debugger.LoadBreakpoints(debugRoot)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	buffer.Breakpoints = bps
+	for fname, lines := range bps {
+		syncedBreakpoints[fname] = append([]int(nil), lines...)
+	}
+}
+
+// CloseDebug unregisters from the DBGp proxy and closes the debugger
+// connections when the editor quits
+func CloseDebug() {
+	if xc != nil {
+		xc.Shutdown()
+	}
+	if dc != nil {
+		dc.Shutdown()
+	}
+}
+
+// debugStatus returns the debugger state for the statusline
+func debugStatus(b *buffer.Buffer) string {
+	if dbg == nil {
+		return ""
+	}
+	if s := dbg.Status(); s != "" {
+		return "[" + backendName() + ": " + s + "] "
+	}
+	return ""
+}
+
+// backendName returns the name of the active backend for messages
+func backendName() string {
+	if _, ok := dbg.(*dap.Client); ok {
+		return "dap"
+	}
+	return "php"
+}
+
+// debugEditor implements debugger.Editor
+type debugEditor struct {
+	*BufPane
+	*InfoPane
+}
+
+func (e debugEditor) VariablesChanged() {
+	// children of watches and console values are loaded as variables
+	refreshDebugPane(varsPaneName)
+	refreshDebugPane(watchPaneName)
+	refreshDebugPane(consolePaneName)
+}
+
+func (e debugEditor) WatchesChanged() {
+	refreshDebugPane(watchPaneName)
+}
+
+func (e debugEditor) StackChanged() {
+	refreshDebugPane(stackPaneName)
+}
+
+func (e debugEditor) SessionsChanged() {
+	refreshDebugPane(sessionsPaneName)
+}
+
+// LocationChanged highlights the line the debugger is stopped at and shows
+// the values of variables assigned on the executed lines
+func (e debugEditor) LocationChanged() {
+	buffer.DebugLine.Path = ""
+	buffer.InlineValues = nil
+
+	uri, line := dbg.Location()
+	if uri == "" {
+		return
+	}
+	// source without local file is opened with the engine URI as path
+	fname, err := dbg.LocalFile(uri)
+	if err == nil {
+		fname, err = filepath.Abs(fname)
+	}
+	if err != nil {
+		fname = uri
+	}
+	buffer.DebugLine.Path = fname
+	buffer.DebugLine.Line = line - 1
+
+	buffer.InlineValues = make(map[int]string)
+	for l, props := range dbg.InlineValues() {
+		var values []string
+		for _, p := range props {
+			if p != nil {
+				values = append(values, describeValue(p.FullName, p))
+			}
+		}
+		if len(values) > 0 {
+			buffer.InlineValues[l-1] = strings.Join(values, ", ")
+		}
+	}
+}
+
+// debugger returns the active debugger backend, the backend of the
+// debugbackend option if no debug command was run yet
+func (h *BufPane) debugger() debugger.Backend {
+	if dbg == nil {
+		h.selectBackend(config.GetGlobalOption("debugbackend").(string))
+	}
+	return dbg
+}
+
+// selectBackend makes the backend with the name active, it is created with
+// the breakpoints and watches of the project the first time
+func (h *BufPane) selectBackend(name string) {
+	if name == "dap" {
+		if dc == nil {
+			dc = &dap.Client{Editor: h.initEditor(), Root: debugRoot, SessionIDs: &sessionIDs}
+			initBackend(dc)
+		}
+		dbg = dc
+		return
+	}
+	dbg = h.xdebugClient()
+}
+
+// xdebugClient returns the xdebug backend, it also loads profiles and traces
+func (h *BufPane) xdebugClient() *xdebug.Client {
 	if xc == nil {
-		p := struct {
-			*BufPane
-			*InfoPane
-		}{
-			h,
-			InfoBar,
+		xc = &xdebug.Client{Editor: h.initEditor(), Root: debugRoot, SessionIDs: &sessionIDs}
+		initBackend(xc)
+	}
+	return xc
+}
+
+// initEditor returns the editor shared by the backends, files are opened
+// in the pane which created it
+func (h *BufPane) initEditor() debugger.Editor {
+	if editor == nil {
+		editor = debugEditor{h, InfoBar}
+	}
+	return editor
+}
+
+func initBackend(b debugger.Backend) {
+	for fname, lines := range buffer.Breakpoints {
+		b.SetBreakpoints(fname, debugLines(lines))
+	}
+
+	watches, err := debugger.LoadWatches(debugRoot)
+	if err != nil {
+		log.Println(err)
+		InfoBar.Error(err)
+	}
+	b.SetWatches(watches)
+}
+
+// setListenOptions passes the debug options to the backend. They are the
+// defaults of .micro/debug.yaml, which is read when the session starts.
+func setListenOptions(b debugger.Backend) {
+	if dc, ok := b.(*dap.Client); ok {
+		dc.Options = dap.Config{
+			Adapter: config.GetGlobalOption("debugadapter").(string),
 		}
+		return
+	}
+	xc := b.(*xdebug.Client)
+	xc.Options = xdebug.Config{
+		Address:     config.GetGlobalOption("debugaddress").(string),
+		Port:        int(config.GetGlobalOption("debugport").(float64)),
+		Timeout:     int(config.GetGlobalOption("debugtimeout").(float64)),
+		IDEKey:      config.GetGlobalOption("debugidekey").(string),
+		BreakFirst:  config.GetGlobalOption("debugfirstline").(bool),
+		Proxy:       config.GetGlobalOption("debugproxy").(string),
+		StepFilters: strings.Fields(config.GetGlobalOption("debugfilter").(string)),
+		MaxChildren: int(config.GetGlobalOption("debugchildren").(float64)),
+		MaxData:     int(config.GetGlobalOption("debugmaxdata").(float64)),
+		MaxDepth:    int(config.GetGlobalOption("debugdepth").(float64)),
+		Record:      config.GetGlobalOption("debugrecord").(string),
+	}
+	// commands like demo run without reading the debug file
+	xc.Config = xc.Options
+}
+
+// debugLines converts 0-based buffer lines to 1-based debugger lines
+func debugLines(lines []int) []int {
+	var res []int
+	for _, l := range lines {
+		res = append(res, l+1)
+	}
+	return res
+}
+
+// phpCommands are the subcommands of the php and debug commands
+var phpCommands = []string{"start", "stop", "listen", "proxyinit", "proxystop", "s", "n", "so", "c", "runto", "break", "detach", "b", "bl", "bp", "e", "set", "vars", "watch", "stack", "session", "sessions", "output", "console", "profile", "trace", "demo", "replay"}
 
-		xc = &xdebug.Client{Editor: p}
+// PhpCmd runs the debugger command with the xdebug backend
+func (h *BufPane) PhpCmd(args []string) {
+	h.selectBackend("xdebug")
+	h.runDebugCmd(args)
+}
+
+// DebugCmd runs the debugger command with the backend of the debugbackend
+// option
+func (h *BufPane) DebugCmd(args []string) {
+	h.selectBackend(config.GetGlobalOption("debugbackend").(string))
+	h.runDebugCmd(args)
+}
+
+func (h *BufPane) runDebugCmd(args []string) {
+	var t string
+	if len(args) > 0 {
+		t = args[0]
 	}
 
-	if err := xc.ProcessCommand(args); err != nil {
+	var err error
+	switch t {
+	case "bp":
+		err = h.breakpointCmd(args[1:])
+	case "watch":
+		err = h.watchCmd(args[1:])
+	case "set":
+		err = h.setCmd(args[1:])
+	case "runto":
+		h.RunToCursor()
+	case "vars":
+		h.openVariables()
+	case "stack":
+		h.openStack()
+	case "sessions":
+		h.openSessions()
+	case "output":
+		err = h.outputCmd(args[1:])
+	case "e", "console":
+		h.consoleCmd(args[1:])
+	case "profile":
+		err = h.profileCmd(args[1:])
+	case "trace":
+		err = h.traceCmd(args[1:])
+	default:
+		// the options apply from the next start, not to a running session
+		if dbg.Status() == "" {
+			setListenOptions(dbg)
+		}
+		err = dbg.ProcessCommand(args)
+	}
+	if err != nil {
 		log.Println(err)
-		xc.Editor.Error(err)
+		editor.Error(err)
+	}
+}
+
+// breakpointCmd lists, adds and removes command line breakpoints:
+//
+//	bp
+//	bp remove N
+//	bp line|cond|call|return|exception ARGS...
+func (h *BufPane) breakpointCmd(args []string) error {
+	if len(args) == 0 {
+		var b bytes.Buffer
+		h.debugger().ListBreakpoints(&b)
+		if b.Len() == 0 {
+			InfoBar.Message("No breakpoints")
+			return nil
+		}
+		InfoBar.Message(strings.Replace(strings.TrimSpace(b.String()), "\n", "; ", -1))
+		return nil
+	}
+
+	if args[0] == "remove" {
+		if len(args) != 2 {
+			return errors.New("usage: php bp remove N")
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return err
+		}
+		return h.debugger().RemoveBreakpoint(n)
+	}
+
+	bp, err := debugger.ParseBreakpoint(args, h.Buf.AbsPath, h.Cursor.Y+1)
+	if err != nil {
+		return err
+	}
+	if err := h.debugger().AddBreakpoint(bp); err != nil {
+		return err
+	}
+	InfoBar.Message("Breakpoint added: ", bp.String())
+	return nil
+}
+
+// RunToCursor continues the debugger to the cursor line
+func (h *BufPane) RunToCursor() bool {
+	if h.Buf.AbsPath == "" || h.Buf.Type != buffer.BTDefault {
+		InfoBar.Error("Run to cursor works in files only")
+		return false
+	}
+	if err := h.debugger().RunTo(h.Buf.AbsPath, h.Cursor.Y+1); err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	return true
+}
+
+// PhpComplete autocompletes php subcommands and breakpoint arguments
+func PhpComplete(b *buffer.Buffer) ([]string, []string) {
+	return debugComplete(b, "xdebug")
+}
+
+// DebugComplete is PhpComplete for the backend of the debugbackend option
+func DebugComplete(b *buffer.Buffer) ([]string, []string) {
+	return debugComplete(b, config.GetGlobalOption("debugbackend").(string))
+}
+
+func debugComplete(b *buffer.Buffer, backend string) ([]string, []string) {
+	c := b.GetActiveCursor()
+	l := b.LineBytes(c.Y)
+	l = util.SliceStart(l, c.X)
+	input, argstart := buffer.GetArg(b)
+
+	args := strings.Split(string(l), " ")
+
+	var options []string
+	switch {
+	case len(args) == 2:
+		options = phpCommands
+	case len(args) == 3 && args[1] == "bp":
+		options = append([]string{"remove"}, debugger.BreakpointTypes...)
+	case len(args) == 3 && args[1] == "start" && backend == "dap":
+		options = dap.LaunchNames(debugRoot)
+	case len(args) == 3 && args[1] == "start":
+		options = xdebug.LaunchNames(debugRoot)
+	case len(args) == 3 && (args[1] == "profile" || args[1] == "trace" || args[1] == "demo" || args[1] == "replay"):
+		return buffer.FileComplete(b)
+	case len(args) == 3 && args[1] == "watch":
+		options = []string{"add", "remove"}
+	case len(args) == 4 && args[1] == "bp" && (args[2] == "line" || args[2] == "cond"):
+		return buffer.FileComplete(b)
+	case len(args) == 4 && args[1] == "bp" && args[2] == "exception" && backend == "dap":
+		options = []string{"*"}
+	case len(args) == 4 && args[1] == "bp" && args[2] == "exception":
+		options = []string{"*", "Exception", "Error", "ErrorException", "TypeError", "RuntimeException", "LogicException", "InvalidArgumentException"}
+	case len(args) >= 4 && args[1] == "bp" && args[2] != "remove":
+		for _, op := range debugger.HitConditions {
+			options = append(options, "hit"+op)
+		}
+	}
+
+	var suggestions []string
+	for _, o := range options {
+		if strings.HasPrefix(o, input) {
+			suggestions = append(suggestions, o)
+		}
+	}
+
+	completions := make([]string, len(suggestions))
+	for i := range suggestions {
+		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
+	}
+	return completions, suggestions
+}
+
+// ToggleBreakpoint sets or removes the debugger breakpoint on the current line
+func (h *BufPane) ToggleBreakpoint() bool {
+	if h.Buf.AbsPath == "" || h.Buf.Type != buffer.BTDefault {
+		InfoBar.Error("Breakpoints can be set in files only")
+		return false
+	}
+
+	if h.Buf.ToggleBreakpoint(h.Cursor.Y) {
+		InfoBar.Message("Breakpoint set at line ", h.Cursor.Y+1)
+	} else {
+		InfoBar.Message("Breakpoint removed at line ", h.Cursor.Y+1)
+	}
+	h.syncBreakpoints()
+	return true
+}
+
+// syncBreakpoints saves breakpoints of the project and passes breakpoints
+// of the buffer to the debugger if they changed since the last sync
+func (h *BufPane) syncBreakpoints() {
+	fname := h.Buf.AbsPath
+	lines := buffer.Breakpoints[fname]
+	if equalLines(lines, syncedBreakpoints[fname]) {
+		return
+	}
+	syncedBreakpoints[fname] = append([]int(nil), lines...)
+
+	if err := debugger.SaveBreakpoints(debugRoot, buffer.Breakpoints); err != nil {
+		log.Println(err)
+		InfoBar.Error(err)
+	}
+	// every backend keeps breakpoints for its next session
+	if xc != nil {
+		xc.SetBreakpoints(fname, debugLines(lines))
+	}
+	if dc != nil {
+		dc.SetBreakpoints(fname, debugLines(lines))
+	}
+}
+
+func equalLines(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
 	}
+	return true
 }
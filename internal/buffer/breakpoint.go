@@ -0,0 +1,71 @@
+package buffer
+
+import "sort"
+
+// Breakpoints maps absolute file paths to sorted 0-based line numbers of
+// debugger breakpoints. It holds breakpoints of files which are not open too,
+// so it is loaded and saved as a whole by the debugger.
+var Breakpoints = make(map[string][]int)
+
+// HasBreakpoints returns true if the buffer has any breakpoints
+func (b *SharedBuffer) HasBreakpoints() bool {
+	return len(Breakpoints[b.AbsPath]) > 0
+}
+
+// HasBreakpoint returns true if there is a breakpoint on the given line
+func (b *SharedBuffer) HasBreakpoint(line int) bool {
+	lines := Breakpoints[b.AbsPath]
+	i := sort.SearchInts(lines, line)
+	return i < len(lines) && lines[i] == line
+}
+
+// ToggleBreakpoint adds or removes the breakpoint on the given line and
+// returns true if the breakpoint was added
+func (b *SharedBuffer) ToggleBreakpoint(line int) bool {
+	if b.AbsPath == "" {
+		return false
+	}
+
+	lines := Breakpoints[b.AbsPath]
+	i := sort.SearchInts(lines, line)
+	if i < len(lines) && lines[i] == line {
+		lines = append(lines[:i], lines[i+1:]...)
+		if len(lines) == 0 {
+			delete(Breakpoints, b.AbsPath)
+		} else {
+			Breakpoints[b.AbsPath] = lines
+		}
+		return false
+	}
+
+	lines = append(lines, 0)
+	copy(lines[i+1:], lines[i:])
+	lines[i] = line
+	Breakpoints[b.AbsPath] = lines
+	return true
+}
+
+// shiftBreakpoints moves breakpoints on lines >= from by delta lines. When
+// lines are removed (delta < 0) breakpoints on the removed lines are dropped.
+func (b *SharedBuffer) shiftBreakpoints(from, delta int) {
+	lines, ok := Breakpoints[b.AbsPath]
+	if !ok || delta == 0 {
+		return
+	}
+
+	var shifted []int
+	for _, l := range lines {
+		if l >= from {
+			l += delta
+		} else if l >= from+delta {
+			continue
+		}
+		shifted = append(shifted, l)
+	}
+
+	if len(shifted) == 0 {
+		delete(Breakpoints, b.AbsPath)
+	} else {
+		Breakpoints[b.AbsPath] = shifted
+	}
+}
@@ -0,0 +1,33 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakpointsFollowEdits(t *testing.T) {
+	b := NewBufferFromString("a\nb\nc\nd\ne", "", BTDefault)
+	b.AbsPath = "/tmp/breakpoints.php"
+	defer delete(Breakpoints, b.AbsPath)
+
+	assert.True(t, b.ToggleBreakpoint(2))
+	assert.True(t, b.ToggleBreakpoint(4))
+	assert.False(t, b.ToggleBreakpoint(4))
+	assert.True(t, b.ToggleBreakpoint(3))
+	assert.Equal(t, []int{2, 3}, Breakpoints[b.AbsPath])
+
+	// new line above the breakpoints
+	b.Insert(Loc{1, 0}, "\n")
+	assert.Equal(t, []int{3, 4}, Breakpoints[b.AbsPath])
+
+	// new line at the start of the breakpoint line moves it down
+	b.Insert(Loc{0, 3}, "x\n")
+	assert.Equal(t, []int{4, 5}, Breakpoints[b.AbsPath])
+
+	// removing the whole line drops its breakpoint
+	b.Remove(Loc{0, 4}, Loc{0, 5})
+	assert.Equal(t, []int{4}, Breakpoints[b.AbsPath])
+	assert.True(t, b.HasBreakpoint(4))
+	assert.False(t, b.HasBreakpoint(5))
+}
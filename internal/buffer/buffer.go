@@ -63,6 +63,9 @@ var (
 	// BTStdout is a buffer that only writes to stdout
 	// when closed
 	BTStdout = BufType{6, false, true, true}
+	// BTSource is a read-only buffer with source code of the debugger which
+	// has no local file
+	BTSource = BufType{7, true, true, true}
 
 	// ErrFileTooLarge is returned when the file is too large to hash
 	// (fastdirty is automatically enabled)
@@ -132,11 +135,22 @@ func (b *SharedBuffer) insert(pos Loc, value []byte) {
 
 	inslines := bytes.Count(value, []byte{'\n'})
 	b.MarkModified(pos.Y, pos.Y+inslines)
+
+	if pos.X == 0 {
+		b.shiftBreakpoints(pos.Y, inslines)
+	} else {
+		b.shiftBreakpoints(pos.Y+1, inslines)
+	}
 }
 func (b *SharedBuffer) remove(start, end Loc) []byte {
 	b.isModified = true
 	b.HasSuggestions = false
 	defer b.MarkModified(start.Y, end.Y)
+	if start.X == 0 && end.X == 0 {
+		b.shiftBreakpoints(end.Y, start.Y-end.Y)
+	} else {
+		b.shiftBreakpoints(end.Y+1, start.Y-end.Y)
+	}
 	return b.LineArray.remove(start, end)
 }
 
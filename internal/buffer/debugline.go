@@ -0,0 +1,25 @@
+package buffer
+
+// DebugLine is the absolute path and the 0-based line the debugger is
+// stopped at. Path is empty if the debugger is not stopped.
+var DebugLine struct {
+	Path string
+	Line int
+}
+
+// InlineValues maps 0-based lines of the file of DebugLine to the values
+// of variables shown at the end of the lines.
+var InlineValues map[int]string
+
+// IsDebugLine returns true if the debugger is stopped at the given line
+func (b *SharedBuffer) IsDebugLine(line int) bool {
+	return b.AbsPath != "" && b.AbsPath == DebugLine.Path && line == DebugLine.Line
+}
+
+// InlineValue returns the values of variables shown at the end of the line
+func (b *SharedBuffer) InlineValue(line int) string {
+	if b.AbsPath == "" || b.AbsPath != DebugLine.Path {
+		return ""
+	}
+	return InlineValues[line]
+}
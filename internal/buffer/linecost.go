@@ -0,0 +1,18 @@
+package buffer
+
+// LineCosts maps absolute paths to 0-based lines and their costs shown in
+// the gutter, e.g. the share of time of a loaded profile.
+var LineCosts map[string]map[int]string
+
+// HasLineCosts returns true if lines of the buffer have costs
+func (b *SharedBuffer) HasLineCosts() bool {
+	return b.AbsPath != "" && LineCosts[b.AbsPath] != nil
+}
+
+// LineCost returns the cost of the line shown in the gutter
+func (b *SharedBuffer) LineCost(line int) string {
+	if b.AbsPath == "" {
+		return ""
+	}
+	return LineCosts[b.AbsPath][line]
+}
@@ -42,15 +42,21 @@ func init() {
 
 // Options with validators
 var optionValidators = map[string]optionValidator{
-	"autosave":     validateNonNegativeValue,
-	"clipboard":    validateClipboard,
-	"tabsize":      validatePositiveValue,
-	"scrollmargin": validateNonNegativeValue,
-	"scrollspeed":  validateNonNegativeValue,
-	"colorscheme":  validateColorscheme,
-	"colorcolumn":  validateNonNegativeValue,
-	"fileformat":   validateLineEnding,
-	"encoding":     validateEncoding,
+	"autosave":      validateNonNegativeValue,
+	"clipboard":     validateClipboard,
+	"tabsize":       validatePositiveValue,
+	"scrollmargin":  validateNonNegativeValue,
+	"scrollspeed":   validateNonNegativeValue,
+	"colorscheme":   validateColorscheme,
+	"colorcolumn":   validateNonNegativeValue,
+	"debugbackend":  validateDebugBackend,
+	"debugchildren": validateNonNegativeValue,
+	"debugdepth":    validateNonNegativeValue,
+	"debugmaxdata":  validateNonNegativeValue,
+	"debugport":     validatePortValue,
+	"debugtimeout":  validateNonNegativeValue,
+	"fileformat":    validateLineEnding,
+	"encoding":      validateEncoding,
 }
 
 func ReadSettings() error {
@@ -288,7 +294,7 @@ var defaultCommonSettings = map[string]interface{}{
 	"softwrap":       false,
 	"splitbottom":    true,
 	"splitright":     true,
-	"statusformatl":  "$(filename) $(modified)($(line),$(col)) $(status.paste)| ft:$(opt:filetype) | $(opt:fileformat) | $(opt:encoding)",
+	"statusformatl":  "$(filename) $(modified)($(line),$(col)) $(status.paste)$(debug)| ft:$(opt:filetype) | $(opt:fileformat) | $(opt:encoding)",
 	"statusformatr":  "$(bind:ToggleKeyMenu): bindings, $(bind:ToggleHelp): help",
 	"statusline":     true,
 	"syntax":         true,
@@ -325,6 +331,20 @@ var DefaultGlobalOnlySettings = map[string]interface{}{
 	"autosave":       float64(0),
 	"clipboard":      "external",
 	"colorscheme":    "default",
+	"debugadapter":   "dlv dap --client-addr={addr}",
+	"debugaddress":   "172.17.0.1",
+	"debugbackend":   "xdebug",
+	"debugchildren":  float64(100),
+	"debugdepth":     float64(1),
+	"debugfilter":    "",
+	"debugfirstline": true,
+	"debughover":     true,
+	"debugidekey":    "",
+	"debugmaxdata":   float64(8192),
+	"debugport":      float64(9003),
+	"debugproxy":     "",
+	"debugrecord":    "",
+	"debugtimeout":   float64(0),
 	"divchars":       "|-",
 	"divreverse":     true,
 	"infobar":        true,
@@ -438,6 +458,20 @@ func validateNonNegativeValue(option string, value interface{}) error {
 	return nil
 }
 
+func validatePortValue(option string, value interface{}) error {
+	port, ok := value.(float64)
+
+	if !ok {
+		return errors.New("Expected numeric type for " + option)
+	}
+
+	if port < 1 || port > 65535 || port != float64(int(port)) {
+		return errors.New(option + " must be a port number between 1 and 65535")
+	}
+
+	return nil
+}
+
 func validateColorscheme(option string, value interface{}) error {
 	colorscheme, ok := value.(string)
 
@@ -468,6 +502,22 @@ func validateClipboard(option string, value interface{}) error {
 	return nil
 }
 
+func validateDebugBackend(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for debugbackend")
+	}
+
+	switch val {
+	case "xdebug", "dap":
+	default:
+		return errors.New(option + " must be 'xdebug' or 'dap'")
+	}
+
+	return nil
+}
+
 func validateLineEnding(option string, value interface{}) error {
 	endingType, ok := value.(string)
 
@@ -0,0 +1,179 @@
+package dap
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// SetBreakpoints replaces the line breakpoints of the local file and sends
+// them to the adapter if the session is started.
+func (c *Client) SetBreakpoints(fname string, lines []int) {
+	if c.breakpoints == nil {
+		c.breakpoints = make(map[string][]int)
+	}
+	c.breakpoints[fname] = append([]int(nil), lines...)
+	if c.configured() {
+		c.sendBreakpoints(fname)
+	}
+}
+
+// configured returns true if the adapter accepts breakpoints.
+func (c *Client) configured() bool {
+	return c.conn != nil && (c.status == "running" || c.status == "break")
+}
+
+// AddBreakpoint adds the command line breakpoint. Return breakpoints are not
+// supported by the protocol.
+func (c *Client) AddBreakpoint(bp debugger.Breakpoint) error {
+	switch bp.Type {
+	case "line", "conditional", "call", "exception":
+	default:
+		return fmt.Errorf("%s breakpoints are not supported by the debug adapter", bp.Type)
+	}
+	c.extraBreakpoints = append(c.extraBreakpoints, &bp)
+	if c.configured() {
+		c.sendBreakpointsOf(&bp)
+	}
+	return nil
+}
+
+// RemoveBreakpoint removes the n-th breakpoint listed by ListBreakpoints.
+func (c *Client) RemoveBreakpoint(n int) error {
+	if n < 1 || n > len(c.extraBreakpoints) {
+		return fmt.Errorf("no breakpoint %d", n)
+	}
+	bp := c.extraBreakpoints[n-1]
+	c.extraBreakpoints = append(c.extraBreakpoints[:n-1], c.extraBreakpoints[n:]...)
+	if c.configured() {
+		c.sendBreakpointsOf(bp)
+	}
+	return nil
+}
+
+// ListBreakpoints writes the numbered list of the command line breakpoints.
+func (c *Client) ListBreakpoints(w io.Writer) {
+	for i, bp := range c.extraBreakpoints {
+		fmt.Fprintf(w, "%2d %s\n", i+1, bp.String())
+	}
+}
+
+// RunTo continues the program to the line of the local file. A temporary
+// breakpoint is set at the line, it is removed at the next stop.
+func (c *Client) RunTo(fname string, line int) error {
+	if c.status != "break" {
+		return fmt.Errorf("run to cursor: the debugger is not stopped at a break")
+	}
+	c.runTo = &debugger.Breakpoint{Type: "line", File: fname, Line: line}
+	c.sendBreakpoints(fname)
+	if err := c.step("continue"); err != nil {
+		return err
+	}
+	c.Editor.Message(fmt.Sprintf("running to %s:%d", filepath.Base(fname), line))
+	return nil
+}
+
+// removeRunTo removes the temporary breakpoint of RunTo.
+func (c *Client) removeRunTo() {
+	if c.runTo == nil {
+		return
+	}
+	fname := c.runTo.File
+	c.runTo = nil
+	c.sendBreakpoints(fname)
+}
+
+// sendBreakpointsOf sends the breakpoints of the kind of bp.
+func (c *Client) sendBreakpointsOf(bp *debugger.Breakpoint) {
+	switch bp.Type {
+	case "call":
+		c.sendFunctionBreakpoints()
+	case "exception":
+		c.sendExceptionBreakpoints()
+	default:
+		c.sendBreakpoints(bp.File)
+	}
+}
+
+// fileBreakpoints returns the line and conditional breakpoints by file.
+func (c *Client) fileBreakpoints() map[string][]sourceBreakpoint {
+	res := make(map[string][]sourceBreakpoint)
+	for fname, lines := range c.breakpoints {
+		// files without breakpoints are sent to clear them
+		res[fname] = nil
+		for _, l := range lines {
+			res[fname] = append(res[fname], sourceBreakpoint{Line: l})
+		}
+	}
+	bps := c.extraBreakpoints
+	if c.runTo != nil {
+		bps = append(bps[:len(bps):len(bps)], c.runTo)
+	}
+	for _, bp := range bps {
+		if bp.Type != "line" && bp.Type != "conditional" {
+			continue
+		}
+		res[bp.File] = append(res[bp.File], sourceBreakpoint{
+			Line:         bp.Line,
+			Condition:    bp.Expression,
+			HitCondition: hitCondition(bp),
+		})
+	}
+	return res
+}
+
+// sendBreakpoints sends all line breakpoints of the file, the adapter
+// replaces the previous ones.
+func (c *Client) sendBreakpoints(fname string) {
+	bps := c.fileBreakpoints()[fname]
+	sort.SliceStable(bps, func(i, j int) bool { return bps[i].Line < bps[j].Line })
+	if bps == nil {
+		bps = []sourceBreakpoint{}
+	}
+	c.request("setBreakpoints", map[string]interface{}{
+		"source":      source{Name: filepath.Base(fname), Path: fname},
+		"breakpoints": bps,
+	}, nil)
+}
+
+func (c *Client) sendFunctionBreakpoints() {
+	if !c.caps.SupportsFunctionBreakpoints {
+		return
+	}
+	bps := []functionBreakpoint{}
+	for _, bp := range c.extraBreakpoints {
+		if bp.Type == "call" {
+			bps = append(bps, functionBreakpoint{Name: bp.Function, HitCondition: hitCondition(bp)})
+		}
+	}
+	c.request("setFunctionBreakpoints", map[string]interface{}{"breakpoints": bps}, nil)
+}
+
+// sendExceptionBreakpoints enables the exception filters of the adapter
+// named by exception breakpoints, * enables all of them.
+func (c *Client) sendExceptionBreakpoints() {
+	if len(c.caps.ExceptionBreakpointFilters) == 0 {
+		return
+	}
+	filters := []string{}
+	for _, f := range c.caps.ExceptionBreakpointFilters {
+		for _, bp := range c.extraBreakpoints {
+			if bp.Type == "exception" && (bp.Exception == "*" || bp.Exception == f.Filter) {
+				filters = append(filters, f.Filter)
+				break
+			}
+		}
+	}
+	c.request("setExceptionBreakpoints", map[string]interface{}{"filters": filters}, nil)
+}
+
+// hitCondition returns the hit condition of the breakpoint like ">= 3".
+func hitCondition(bp *debugger.Breakpoint) string {
+	if bp.HitCondition == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s %d", bp.HitCondition, bp.HitValue)
+}
@@ -0,0 +1,664 @@
+// Package dap is a Debug Adapter Protocol client, the debugger backend for
+// debug adapters like Delve (dlv dap).
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	shellquote "github.com/kballard/go-shellquote"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+	"github.com/zyedidia/micro/v2/internal/shell"
+)
+
+// DefaultAdapter starts Delve connecting back to the client.
+const DefaultAdapter = "dlv dap --client-addr={addr}"
+
+// connectTimeout is the time to wait for the adapter to connect or to
+// accept the connection.
+const connectTimeout = 10 * time.Second
+
+// Config is the configuration of the client. Options holds the editor
+// options, the dap section of .micro/debug.yaml overrides them.
+type Config struct {
+	// Adapter is the command starting the adapter. {addr} is replaced with
+	// the address of the listener the adapter connects to, without it the
+	// adapter talks over stdin and stdout.
+	Adapter  string   `yaml:"adapter"`
+	Address  string   `yaml:"address"`        // host:port of a running adapter, Adapter is not started if set
+	Launches []Launch `yaml:"configurations"` // named launch configurations for start NAME
+}
+
+// Client is a DAP client with one debug session. The configuration is read
+// from the project debug file on start.
+type Client struct {
+	Config
+
+	Options Config // editor options, the defaults of the project debug file
+
+	Root string // project root, the working directory of the adapter
+
+	Editor debugger.Editor // callback interface for editor automation
+
+	SessionIDs *debugger.SessionCounter // numbers the sessions, a counter of the client if nil
+
+	cmd    *exec.Cmd // adapter process
+	conn   *transport
+	caps   capabilities
+	launch *Launch
+	status string // starting, running or break, empty without session
+	id     int    // ID of the session
+
+	threadID int          // thread of the last stop
+	frames   []stackFrame // stack of the last stop
+	depth    int          // selected frame
+
+	contexts  []*debugger.Context
+	scopeRefs map[*debugger.Context]int
+	varRefs   map[*debugger.Property]int
+
+	breakpoints      map[string][]int // local file -> 1-based lines of line breakpoints
+	extraBreakpoints []*debugger.Breakpoint
+	runTo            *debugger.Breakpoint // temporary breakpoint of RunTo
+
+	watches []*debugger.Watch
+}
+
+var _ debugger.Backend = (*Client)(nil)
+
+// root returns the project root or the current directory.
+func (c *Client) root() string {
+	if c.Root != "" {
+		return c.Root
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// Start starts the adapter, connects to it and launches the program of the
+// launch configuration. Without a name the Go package in the project root is
+// debugged.
+func (c *Client) Start(name string) error {
+	if c.conn != nil || c.status != "" {
+		return fmt.Errorf("start: the debug session is already started")
+	}
+	if err := c.readConfig(); err != nil {
+		return err
+	}
+	if err := c.selectLaunch(name); err != nil {
+		return err
+	}
+
+	c.newSession()
+	err := c.connect(func(conn io.ReadWriteCloser, err error) {
+		if err != nil {
+			c.end()
+			c.Editor.Error(err)
+			return
+		}
+		c.connected(conn)
+	})
+	if err != nil {
+		c.end()
+	}
+	return err
+}
+
+// newSession numbers the session and marks it as starting.
+func (c *Client) newSession() {
+	if c.SessionIDs == nil {
+		c.SessionIDs = &debugger.SessionCounter{}
+	}
+	c.id = c.SessionIDs.Next()
+	c.status = "starting"
+	c.Editor.SessionsChanged()
+}
+
+// connect connects to the adapter in the background and calls done on the
+// main loop.
+func (c *Client) connect(done func(io.ReadWriteCloser, error)) error {
+	if c.Address != "" {
+		go func() {
+			conn, err := net.DialTimeout("tcp", c.Address, connectTimeout)
+			if err != nil {
+				err = fmt.Errorf("dap connect. error: %w", err)
+			}
+			shell.Post(func() { done(conn, err) })
+		}()
+		return nil
+	}
+
+	adapter := c.Adapter
+	if adapter == "" {
+		adapter = DefaultAdapter
+	}
+
+	var ln net.Listener
+	if strings.Contains(adapter, "{addr}") {
+		var err error
+		ln, err = net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return fmt.Errorf("dap listen. error: %w", err)
+		}
+		adapter = strings.Replace(adapter, "{addr}", ln.Addr().String(), -1)
+	}
+
+	args, err := shellquote.Split(adapter)
+	if err != nil || len(args) == 0 {
+		if ln != nil {
+			ln.Close()
+		}
+		return fmt.Errorf("dap adapter %q: bad command", adapter)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = c.root()
+	cmd.Stderr = logWriter{}
+
+	var stdio io.ReadWriteCloser
+	if ln == nil {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return fmt.Errorf("dap adapter. error: %w", err)
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return fmt.Errorf("dap adapter. error: %w", err)
+		}
+		stdio = pipe{stdout, stdin}
+	} else {
+		cmd.Stdout = logWriter{}
+	}
+
+	log.Println("dap adapter:", cmd.Args)
+	if err := cmd.Start(); err != nil {
+		if ln != nil {
+			ln.Close()
+		}
+		return fmt.Errorf("dap adapter start. error: %w", err)
+	}
+	c.cmd = cmd
+
+	if ln == nil {
+		shell.Post(func() { done(stdio, nil) })
+		return nil
+	}
+
+	go func() {
+		defer ln.Close()
+		ln.(*net.TCPListener).SetDeadline(time.Now().Add(connectTimeout))
+		conn, err := ln.Accept()
+		if err != nil {
+			err = fmt.Errorf("dap accept. error: %w", err)
+		}
+		shell.Post(func() { done(conn, err) })
+	}()
+	return nil
+}
+
+// pipe is the stdout and stdin of an adapter talking over stdio.
+type pipe struct {
+	io.ReadCloser
+	w io.WriteCloser
+}
+
+func (p pipe) Write(b []byte) (int, error) {
+	return p.w.Write(b)
+}
+
+func (p pipe) Close() error {
+	p.w.Close()
+	return p.ReadCloser.Close()
+}
+
+// logWriter logs the output of the adapter.
+type logWriter struct{}
+
+func (logWriter) Write(b []byte) (int, error) {
+	log.Print("dap adapter: ", string(b))
+	return len(b), nil
+}
+
+// connected initializes the adapter and launches the program. Breakpoints
+// are set when the adapter sends the initialized event.
+func (c *Client) connected(conn io.ReadWriteCloser) {
+	c.conn = newTransport(conn)
+	c.conn.onEvent = c.handleEvent
+	c.conn.onRequest = func(m message) {
+		// reverse requests like runInTerminal are not supported
+		if err := c.conn.reply(m, fmt.Errorf("%s is not supported", m.Command)); err != nil {
+			log.Println(err)
+		}
+	}
+	c.conn.onClose = func(err error) {
+		if err != nil {
+			c.Editor.Error(fmt.Sprintf("debug adapter: %v", err))
+		}
+		c.end()
+	}
+	c.conn.start()
+
+	args := map[string]interface{}{
+		"clientID":                     "micro",
+		"clientName":                   "micro",
+		"adapterID":                    c.adapterName(),
+		"linesStartAt1":                true,
+		"columnsStartAt1":              true,
+		"pathFormat":                   "path",
+		"supportsVariableType":         true,
+		"supportsRunInTerminalRequest": false,
+	}
+	c.request("initialize", args, func(m message) {
+		if err := json.Unmarshal(m.Body, &c.caps); err != nil {
+			log.Println(err)
+		}
+		request, largs := c.launchArgs()
+		c.request(request, largs, nil)
+	})
+}
+
+// adapterName returns the name of the adapter command for the sessions pane.
+func (c *Client) adapterName() string {
+	args, err := shellquote.Split(c.Adapter)
+	if err != nil || len(args) == 0 {
+		return "dlv"
+	}
+	return filepath.Base(args[0])
+}
+
+// request sends the request and calls h with successful responses. Failed
+// requests are reported to the editor.
+func (c *Client) request(cmd string, args interface{}, h handler) {
+	if c.conn == nil {
+		c.Editor.Error(cmd + ": no debug session")
+		return
+	}
+	err := c.conn.send(cmd, args, func(m message) {
+		if !m.Success {
+			c.Editor.Error(fmt.Sprintf("%s: %s", cmd, m.Message))
+			return
+		}
+		if h != nil {
+			h(m)
+		}
+	})
+	if err != nil {
+		log.Println(err)
+		c.Editor.Error(err)
+	}
+}
+
+func (c *Client) handleEvent(m message) {
+	switch m.Event {
+	case "initialized":
+		c.configure()
+	case "stopped":
+		var ev stoppedEvent
+		if err := json.Unmarshal(m.Body, &ev); err != nil {
+			log.Println(err)
+		}
+		c.stopped(ev)
+	case "continued":
+		c.running()
+	case "output":
+		var ev outputEvent
+		if err := json.Unmarshal(m.Body, &ev); err != nil {
+			log.Println(err)
+		}
+		if ev.Category != "telemetry" {
+			c.Editor.Output(c.id, ev.Output)
+		}
+	case "exited":
+		var ev struct {
+			ExitCode int `json:"exitCode"`
+		}
+		json.Unmarshal(m.Body, &ev)
+		c.Editor.Message(fmt.Sprintf("debug: program exited with code %d", ev.ExitCode))
+	case "terminated":
+		c.Stop()
+	}
+}
+
+// configure sets the breakpoints after the adapter is initialized and
+// finishes the configuration.
+func (c *Client) configure() {
+	for fname := range c.fileBreakpoints() {
+		c.sendBreakpoints(fname)
+	}
+	c.sendFunctionBreakpoints()
+	c.sendExceptionBreakpoints()
+	if c.caps.SupportsConfigurationDoneRequest {
+		c.request("configurationDone", nil, nil)
+	}
+	c.status = "running"
+	c.Editor.SessionsChanged()
+}
+
+// stopped fetches the stack of the stopped thread and opens the location.
+func (c *Client) stopped(ev stoppedEvent) {
+	c.status = "break"
+	c.threadID = ev.ThreadID
+	c.removeRunTo()
+
+	args := map[string]interface{}{"threadId": ev.ThreadID, "startFrame": 0, "levels": 50}
+	c.request("stackTrace", args, func(m message) {
+		var resp stackTraceResponse
+		if err := json.Unmarshal(m.Body, &resp); err != nil {
+			log.Println(err)
+			return
+		}
+		c.frames = resp.StackFrames
+		c.depth = 0
+		if len(c.frames) > 0 {
+			f := c.frames[0]
+			c.jumpTo(f)
+			c.Editor.Message(fmt.Sprintf("break at %s:%d (%s)", filepath.Base(f.Source.Path), f.Line, ev.Reason))
+		}
+		c.refreshVariables()
+		c.evalWatches()
+		c.Editor.StackChanged()
+		c.Editor.LocationChanged()
+	})
+	c.Editor.SessionsChanged()
+}
+
+// running forgets the state of the last stop.
+func (c *Client) running() {
+	c.status = "running"
+	c.frames = nil
+	c.depth = 0
+	// variables references are valid until the program continues
+	c.varRefs = nil
+	c.Editor.StackChanged()
+	c.Editor.SessionsChanged()
+	c.Editor.LocationChanged()
+}
+
+// jumpTo opens the source of the frame at its line.
+func (c *Client) jumpTo(f stackFrame) {
+	if f.Source.Path == "" {
+		c.Editor.Message(fmt.Sprintf("%s: no source", f.Name))
+		return
+	}
+	c.Editor.OpenCmd([]string{f.Source.Path})
+	c.Editor.GotoCmd([]string{strconv.Itoa(f.Line)})
+}
+
+// step sends the step command for the stopped thread.
+func (c *Client) step(cmd string) error {
+	if c.status != "break" {
+		return fmt.Errorf("%s: the program is not stopped at a break", cmd)
+	}
+	c.request(cmd, map[string]interface{}{"threadId": c.threadID}, nil)
+	c.running()
+	return nil
+}
+
+// Stop terminates the program and closes the session.
+func (c *Client) Stop() {
+	if c.conn == nil {
+		c.end()
+		return
+	}
+	conn := c.conn
+	err := conn.send("disconnect", map[string]interface{}{"terminateDebuggee": true}, func(message) {
+		conn.close()
+	})
+	if err != nil {
+		conn.close()
+	}
+}
+
+// end forgets the session and stops the adapter.
+func (c *Client) end() {
+	if c.status == "" && c.conn == nil {
+		return
+	}
+	if c.conn != nil {
+		c.conn.close()
+		c.conn = nil
+	}
+	if c.cmd != nil {
+		cmd := c.cmd
+		c.cmd = nil
+		go func() {
+			time.Sleep(time.Second)
+			cmd.Process.Kill()
+			cmd.Wait()
+		}()
+	}
+	c.status = ""
+	c.frames = nil
+	c.contexts = nil
+	c.varRefs = nil
+	c.runTo = nil
+	c.Editor.Message("debug session ended")
+	c.Editor.VariablesChanged()
+	c.Editor.StackChanged()
+	c.Editor.SessionsChanged()
+	c.Editor.LocationChanged()
+}
+
+// Shutdown closes the session when the editor quits.
+func (c *Client) Shutdown() {
+	if c.conn != nil {
+		c.conn.send("disconnect", map[string]interface{}{"terminateDebuggee": true}, nil)
+		c.conn.close()
+	}
+	if c.cmd != nil {
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+	}
+}
+
+// Status returns the status of the session for the statusline.
+func (c *Client) Status() string {
+	return c.status
+}
+
+// Sessions returns the session of the adapter, if any.
+func (c *Client) Sessions() []debugger.SessionInfo {
+	if c.status == "" {
+		return nil
+	}
+	file := ""
+	if c.launch != nil {
+		file = fmt.Sprint(c.launch.Arguments["program"])
+	}
+	return []debugger.SessionInfo{{
+		ID:       c.id,
+		File:     file,
+		Language: c.adapterName(),
+		Status:   c.status,
+		Current:  true,
+	}}
+}
+
+func (c *Client) checkSession(id int) error {
+	if id != c.id || c.status == "" {
+		return fmt.Errorf("no debug session %d", id)
+	}
+	return nil
+}
+
+// SelectSession does nothing, the client has one session.
+func (c *Client) SelectSession(id int) error {
+	return c.checkSession(id)
+}
+
+// DetachSession disconnects from the program, which continues without the
+// debugger.
+func (c *Client) DetachSession(id int) error {
+	if err := c.checkSession(id); err != nil {
+		return err
+	}
+	conn := c.conn
+	c.request("disconnect", map[string]interface{}{"terminateDebuggee": false}, func(message) {
+		conn.close()
+	})
+	return nil
+}
+
+// StopSession terminates the program of the session.
+func (c *Client) StopSession(id int) error {
+	if err := c.checkSession(id); err != nil {
+		return err
+	}
+	c.Stop()
+	return nil
+}
+
+// ProcessCommand runs the session commands:
+//
+//	start [NAME], stop, detach, s, n, so, c, break
+func (c *Client) ProcessCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("debug: command expected")
+	}
+
+	switch args[0] {
+	case "start":
+		name := ""
+		if len(args) > 1 {
+			name = args[1]
+		}
+		return c.Start(name)
+	case "stop":
+		c.Stop()
+	case "detach":
+		return c.DetachSession(c.id)
+	case "s":
+		return c.step("stepIn")
+	case "n":
+		return c.step("next")
+	case "so":
+		return c.step("stepOut")
+	case "c":
+		return c.step("continue")
+	case "break":
+		if c.status != "running" {
+			return fmt.Errorf("break: the program is not running")
+		}
+		c.pause()
+	default:
+		return fmt.Errorf("%s: not supported by the debug adapter", args[0])
+	}
+	return nil
+}
+
+// pause pauses the thread of the last stop. Before the first stop the
+// thread is the first one of the threads of the program.
+func (c *Client) pause() {
+	if c.threadID != 0 {
+		c.request("pause", map[string]interface{}{"threadId": c.threadID}, nil)
+		return
+	}
+	c.request("threads", nil, func(m message) {
+		var resp threadsResponse
+		if err := json.Unmarshal(m.Body, &resp); err != nil {
+			log.Println(err)
+			return
+		}
+		if len(resp.Threads) == 0 {
+			c.Editor.Error("break: the program has no threads")
+			return
+		}
+		c.request("pause", map[string]interface{}{"threadId": resp.Threads[0].ID}, nil)
+	})
+}
+
+// Location returns the file URI and the line of the selected frame.
+func (c *Client) Location() (string, int) {
+	if c.status != "break" || c.depth >= len(c.frames) {
+		return "", 0
+	}
+	f := c.frames[c.depth]
+	if f.Source.Path == "" {
+		return "", 0
+	}
+	return pathToURI(f.Source.Path), f.Line
+}
+
+// InlineValues returns no values, adapters do not tell assigned variables.
+func (c *Client) InlineValues() map[int][]*debugger.Property {
+	return nil
+}
+
+// LocalPath returns the path of the file URI. Adapters use local paths.
+func (c *Client) LocalPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", fmt.Errorf("%s: not a file URI", uri)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
+
+// LocalFile returns the path of the file URI if the file exists.
+func (c *Client) LocalFile(uri string) (string, error) {
+	fname, err := c.LocalPath(uri)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(fname); err != nil {
+		return "", fmt.Errorf("%s: no local file", uri)
+	}
+	return fname, nil
+}
+
+// pathToURI encodes the path as a file URI.
+func pathToURI(p string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(p)}
+	return u.String()
+}
+
+// Stack returns the stack frames of the stopped thread, the innermost first.
+func (c *Client) Stack() []debugger.Frame {
+	var res []debugger.Frame
+	for i, f := range c.frames {
+		res = append(res, debugger.Frame{
+			Level: i,
+			File:  pathToURI(f.Source.Path),
+			Line:  f.Line,
+			Where: f.Name,
+		})
+	}
+	return res
+}
+
+// Depth returns the level of the selected stack frame.
+func (c *Client) Depth() int {
+	return c.depth
+}
+
+// SelectFrame opens the source of the frame and switches variables to it.
+func (c *Client) SelectFrame(level int) error {
+	if level < 0 || level >= len(c.frames) {
+		return fmt.Errorf("no stack frame %d", level)
+	}
+	c.jumpTo(c.frames[level])
+	if c.depth != level {
+		c.depth = level
+		c.contexts = nil
+		c.refreshVariables()
+	}
+	c.Editor.StackChanged()
+	c.Editor.LocationChanged()
+	return nil
+}
+
+// frameID returns the adapter ID of the frame at the level.
+func (c *Client) frameID(level int) (int, bool) {
+	if c.status != "break" || level < 0 || level >= len(c.frames) {
+		return 0, false
+	}
+	return c.frames[level].ID, true
+}
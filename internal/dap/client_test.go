@@ -0,0 +1,278 @@
+package dap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// testEditor records messages, errors and opened files of the client.
+type testEditor struct {
+	messages []string
+	errors   []string
+	opened   []string
+	output   string
+	session  int // session of the last output
+}
+
+func (e *testEditor) OpenCmd(args []string)          { e.opened = append(e.opened, args[0]) }
+func (e *testEditor) GotoCmd(args []string)          { e.opened = append(e.opened, args[0]) }
+func (e *testEditor) Message(msg ...interface{})     { e.messages = append(e.messages, sprint(msg)) }
+func (e *testEditor) Error(msg ...interface{})       { e.errors = append(e.errors, sprint(msg)) }
+func (e *testEditor) VariablesChanged()              {}
+func (e *testEditor) WatchesChanged()                {}
+func (e *testEditor) StackChanged()                  {}
+func (e *testEditor) SessionsChanged()               {}
+func (e *testEditor) LocationChanged()               {}
+func (e *testEditor) OpenSource(string, string, int) {}
+func (e *testEditor) Output(session int, text string) {
+	e.output += text
+	e.session = session
+}
+
+func sprint(msg []interface{}) string {
+	return strings.TrimSpace(fmt.Sprint(msg...))
+}
+
+// startSession connects the client to the adapter end of a pipe and
+// answers the requests up to configurationDone.
+func startSession(t *testing.T, c *Client) (net.Conn, <-chan message) {
+	ide, adapter := net.Pipe()
+	reqs := adapterRequests(adapter)
+	assert.NoError(t, c.selectLaunch(""))
+	c.newSession()
+	c.connected(ide)
+
+	respond(t, adapter, nextRequest(t, reqs, "initialize"),
+		`{"supportsConfigurationDoneRequest":true,"supportsFunctionBreakpoints":true,"supportsSetVariable":true}`)
+	runJob(t)
+	launch := nextRequest(t, reqs, "launch")
+	assert.JSONEq(t, fmt.Sprintf(`{"mode":"debug","program":%q}`, c.Root), arguments(t, launch))
+
+	event(t, adapter, "initialized", `{}`)
+	runJob(t)
+	return adapter, reqs
+}
+
+func TestSession(t *testing.T) {
+	ed := &testEditor{}
+	// the xdebug backend sharing the counter numbered session 1
+	ids := &debugger.SessionCounter{}
+	ids.Next()
+	c := &Client{Editor: ed, Root: "/p", SessionIDs: ids}
+	c.SetBreakpoints("/p/main.go", []int{5})
+	assert.NoError(t, c.AddBreakpoint(debugger.Breakpoint{Type: "call", Function: "main.f", HitCondition: ">=", HitValue: 2}))
+	assert.Error(t, c.AddBreakpoint(debugger.Breakpoint{Type: "return", Function: "main.f"}))
+	c.SetWatches([]string{"len(s)"})
+
+	adapter, reqs := startSession(t, c)
+	defer adapter.Close()
+	assert.JSONEq(t, `{"breakpoints":[{"line":5}],"source":{"name":"main.go","path":"/p/main.go"}}`,
+		arguments(t, nextRequest(t, reqs, "setBreakpoints")))
+	assert.JSONEq(t, `{"breakpoints":[{"name":"main.f","hitCondition":">= 2"}]}`,
+		arguments(t, nextRequest(t, reqs, "setFunctionBreakpoints")))
+	nextRequest(t, reqs, "configurationDone")
+	assert.Equal(t, "running", c.Status())
+	assert.Equal(t, []debugger.SessionInfo{{ID: 2, File: "/p", Language: "dlv", Status: "running", Current: true}}, c.Sessions())
+
+	event(t, adapter, "output", `{"category":"stdout","output":"hello\n"}`)
+	runJob(t)
+	assert.Equal(t, "hello\n", ed.output)
+	assert.Equal(t, 2, ed.session)
+
+	// a stop fetches the stack, the variables and the watches
+	event(t, adapter, "stopped", `{"reason":"breakpoint","threadId":7}`)
+	runJob(t)
+	stack := nextRequest(t, reqs, "stackTrace")
+	assert.JSONEq(t, `{"levels":50,"startFrame":0,"threadId":7}`, arguments(t, stack))
+	respond(t, adapter, stack, `{"stackFrames":[
+		{"id":1000,"name":"main.main","source":{"path":"/p/main.go"},"line":5},
+		{"id":1001,"name":"runtime.main","source":{"path":"/go/proc.go"},"line":250}]}`)
+	runJob(t)
+	assert.Equal(t, []string{"/p/main.go", "5"}, ed.opened)
+	assert.Equal(t, "break at main.go:5 (breakpoint)", ed.messages[len(ed.messages)-1])
+	uri, line := c.Location()
+	assert.Equal(t, "file:///p/main.go", uri)
+	assert.Equal(t, 5, line)
+	assert.Equal(t, debugger.Frame{Level: 1, File: "file:///go/proc.go", Line: 250, Where: "runtime.main"}, c.Stack()[1])
+
+	scopes := nextRequest(t, reqs, "scopes")
+	assert.JSONEq(t, `{"frameId":1000}`, arguments(t, scopes))
+	watch := nextRequest(t, reqs, "evaluate")
+	assert.JSONEq(t, `{"context":"watch","expression":"len(s)","frameId":1000}`, arguments(t, watch))
+	respond(t, adapter, scopes, `{"scopes":[{"name":"Locals","variablesReference":1},{"name":"Globals","variablesReference":2}]}`)
+	runJob(t)
+	respond(t, adapter, watch, `{"result":"2","type":"int"}`)
+	runJob(t)
+	assert.Equal(t, "int 2", c.Watches()[0].Value.Summary())
+
+	vars := nextRequest(t, reqs, "variables")
+	assert.JSONEq(t, `{"variablesReference":1}`, arguments(t, vars))
+	respond(t, adapter, vars, `{"variables":[
+		{"name":"s","value":"\"hi\"","type":"string","evaluateName":"s"},
+		{"name":"t","value":"main.T {A: 1}","type":"main.T","evaluateName":"t","variablesReference":3,"namedVariables":1}]}`)
+	runJob(t)
+	contexts := c.Contexts()
+	assert.Len(t, contexts, 2)
+	assert.True(t, contexts[0].Expanded)
+	assert.False(t, contexts[1].Expanded)
+	props := contexts[0].Properties
+	assert.Equal(t, `string "hi"`, props[0].Summary())
+	assert.Equal(t, 0, props[0].NumChildren)
+	assert.Equal(t, 1, props[1].NumChildren)
+
+	// children are fetched when expanded
+	c.ToggleProperty(props[1])
+	vars = nextRequest(t, reqs, "variables")
+	assert.JSONEq(t, `{"variablesReference":3}`, arguments(t, vars))
+	respond(t, adapter, vars, `{"variables":[{"name":"A","value":"1","type":"int","evaluateName":"t.A"}]}`)
+	runJob(t)
+	assert.Equal(t, "t.A", props[1].Children[0].FullName)
+
+	var value *debugger.Property
+	assert.NoError(t, c.Eval("s+s", func(p *debugger.Property, err error) {
+		assert.NoError(t, err)
+		value = p
+	}))
+	eval := nextRequest(t, reqs, "evaluate")
+	assert.JSONEq(t, `{"context":"repl","expression":"s+s","frameId":1000}`, arguments(t, eval))
+	respond(t, adapter, eval, `{"result":"\"hihi\"","type":"string"}`)
+	runJob(t)
+	assert.Equal(t, "hihi", value.Value)
+
+	// scope variables are set with setVariable
+	var setErr error
+	assert.NoError(t, c.SetVariable(debugger.Assignment{Name: "s", Value: `"x"`}, func(err error) { setErr = err }))
+	set := nextRequest(t, reqs, "setVariable")
+	assert.JSONEq(t, `{"name":"s","value":"\"x\"","variablesReference":1}`, arguments(t, set))
+	writeMessage(t, adapter, fmt.Sprintf(`{"type":"response","request_seq":%d,"command":"setVariable","success":false,"message":"bad value"}`, set.Seq))
+	runJob(t)
+	assert.EqualError(t, setErr, "set s: bad value")
+
+	assert.NoError(t, c.ProcessCommand([]string{"n"}))
+	assert.JSONEq(t, `{"threadId":7}`, arguments(t, nextRequest(t, reqs, "next")))
+	assert.Equal(t, "running", c.Status())
+	assert.Nil(t, c.Stack())
+	assert.Error(t, c.ProcessCommand([]string{"n"}))
+
+	// the terminated event ends the session
+	event(t, adapter, "terminated", `{}`)
+	runJob(t)
+	disconnect := nextRequest(t, reqs, "disconnect")
+	assert.JSONEq(t, `{"terminateDebuggee":true}`, arguments(t, disconnect))
+	respond(t, adapter, disconnect, `{}`)
+	runJob(t)
+	runJob(t)
+	assert.Equal(t, "", c.Status())
+	assert.Nil(t, c.Sessions())
+	assert.Empty(t, ed.errors)
+}
+
+func TestRunTo(t *testing.T) {
+	c := &Client{Editor: &testEditor{}, Root: "/p"}
+	c.SetBreakpoints("/p/main.go", []int{5})
+	adapter, reqs := startSession(t, c)
+	nextRequest(t, reqs, "setBreakpoints")
+	nextRequest(t, reqs, "setFunctionBreakpoints")
+	nextRequest(t, reqs, "configurationDone")
+
+	c.status, c.threadID = "break", 1
+	assert.NoError(t, c.RunTo("/p/main.go", 9))
+	assert.JSONEq(t, `{"breakpoints":[{"line":5},{"line":9}],"source":{"name":"main.go","path":"/p/main.go"}}`,
+		arguments(t, nextRequest(t, reqs, "setBreakpoints")))
+	nextRequest(t, reqs, "continue")
+
+	// the temporary breakpoint is removed at the next stop
+	event(t, adapter, "stopped", `{"reason":"breakpoint","threadId":1}`)
+	runJob(t)
+	assert.JSONEq(t, `{"breakpoints":[{"line":5}],"source":{"name":"main.go","path":"/p/main.go"}}`,
+		arguments(t, nextRequest(t, reqs, "setBreakpoints")))
+	nextRequest(t, reqs, "stackTrace")
+
+	adapter.Close()
+	runJob(t)
+	assert.Equal(t, "", c.Status())
+}
+
+func TestBreak(t *testing.T) {
+	c := &Client{Editor: &testEditor{}, Root: "/p"}
+	adapter, reqs := startSession(t, c)
+	nextRequest(t, reqs, "setFunctionBreakpoints")
+	nextRequest(t, reqs, "configurationDone")
+
+	// before the first stop the thread is taken from the threads
+	assert.NoError(t, c.ProcessCommand([]string{"break"}))
+	threads := nextRequest(t, reqs, "threads")
+	respond(t, adapter, threads, `{"threads":[{"id":3,"name":"main"},{"id":4,"name":"worker"}]}`)
+	runJob(t)
+	assert.JSONEq(t, `{"threadId":3}`, arguments(t, nextRequest(t, reqs, "pause")))
+
+	adapter.Close()
+	runJob(t)
+	assert.Equal(t, "", c.Status())
+}
+
+func TestStartError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dap")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := &Client{Editor: &testEditor{}, Root: dir, Options: Config{Adapter: `dlv "dap`}}
+	assert.Error(t, c.Start(""))
+	assert.Equal(t, "", c.Status())
+	assert.Nil(t, c.Sessions())
+}
+
+func TestLaunchConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dap")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, ".micro"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".micro", "debug.yaml"), []byte(`
+base_path: /var/www
+dap:
+  adapter: dlv dap --client-addr={addr} --log
+  configurations:
+    - name: server
+      arguments:
+        mode: debug
+        program: ./cmd/server
+        args: [-v]
+        env: {PORT: 8080}
+    - name: remote
+      request: attach
+      arguments: {mode: remote}
+`), 0644))
+
+	assert.Equal(t, []string{"server", "remote"}, LaunchNames(dir))
+
+	c := &Client{Root: dir, Options: Config{Adapter: "dlv dap"}}
+	assert.NoError(t, c.readConfig())
+	assert.Equal(t, "dlv", c.adapterName())
+	assert.NoError(t, c.selectLaunch("server"))
+	request, args := c.launchArgs()
+	assert.Equal(t, "launch", request)
+	assert.Equal(t, map[string]interface{}{
+		"mode":    "debug",
+		"program": "./cmd/server",
+		"args":    []interface{}{"-v"},
+		"env":     map[string]interface{}{"PORT": 8080},
+	}, args)
+
+	assert.NoError(t, c.selectLaunch("remote"))
+	request, _ = c.launchArgs()
+	assert.Equal(t, "attach", request)
+	assert.Error(t, c.selectLaunch("nope"))
+
+	// keys removed from the file get the options again
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".micro", "debug.yaml"), []byte("dap: {address: 127.0.0.1:4711}\n"), 0644))
+	assert.NoError(t, c.readConfig())
+	assert.Equal(t, Config{Adapter: "dlv dap", Address: "127.0.0.1:4711"}, c.Config)
+}
@@ -0,0 +1,120 @@
+package dap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+	"gopkg.in/yaml.v2"
+)
+
+// Launch is a named launch configuration of the dap section of the project
+// debug file.
+type Launch struct {
+	Name      string                 `yaml:"name"`
+	Request   string                 `yaml:"request"`   // launch or attach, launch if empty
+	Arguments map[string]interface{} `yaml:"arguments"` // arguments of the adapter, e.g. mode and program of Delve
+}
+
+func debugFile(root string) string {
+	return filepath.Join(root, debugger.ProjectDir, "debug.yaml")
+}
+
+// config is the project debug file, the dap section configures the client.
+type config struct {
+	DAP *Config `yaml:"dap"`
+}
+
+// LaunchNames returns the names of the launch configurations of the
+// project for completion. Errors are only logged.
+func LaunchNames(root string) []string {
+	c := &Client{Root: root}
+	if err := c.readConfig(); err != nil {
+		log.Println(err)
+		return nil
+	}
+	var names []string
+	for _, l := range c.Launches {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// readConfig sets the configuration of the client to the options
+// overridden by the dap section of the project debug file.
+func (c *Client) readConfig() error {
+	c.Config = c.Options
+	b, err := ioutil.ReadFile(debugFile(c.root()))
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("debug.yaml read. error: %w", err)
+	}
+
+	// keys removed from the file get the options again
+	cfg := c.Options
+	if err := yaml.Unmarshal(b, &config{DAP: &cfg}); err != nil {
+		return fmt.Errorf("debug.yaml decode. error: %w", err)
+	}
+	c.Config = cfg
+	return nil
+}
+
+// selectLaunch selects the launch configuration of the session. Without a
+// name the Go package in the project root is debugged.
+func (c *Client) selectLaunch(name string) error {
+	if name == "" {
+		c.launch = &Launch{
+			Name:      "default",
+			Arguments: map[string]interface{}{"mode": "debug", "program": c.root()},
+		}
+		return nil
+	}
+	for i := range c.Launches {
+		if c.Launches[i].Name == name {
+			c.launch = &c.Launches[i]
+			return nil
+		}
+	}
+	return fmt.Errorf("no launch configuration %q in %s", name, debugFile(c.root()))
+}
+
+// launchArgs returns the launch or attach request of the selected launch
+// configuration with its arguments converted for JSON.
+func (c *Client) launchArgs() (string, interface{}) {
+	request := c.launch.Request
+	if request == "" {
+		request = "launch"
+	}
+	return request, jsonValue(c.launch.Arguments)
+}
+
+// jsonValue converts maps decoded from YAML, which have interface{} keys,
+// to maps with string keys.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{})
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonValue(e)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{})
+		for k, e := range v {
+			m[k] = jsonValue(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = jsonValue(e)
+		}
+		return l
+	}
+	return v
+}
@@ -0,0 +1,117 @@
+package dap
+
+import "encoding/json"
+
+// message is a Debug Adapter Protocol request, response or event. Only the
+// fields of its type are set.
+type message struct {
+	Seq  int    `json:"seq"`
+	Type string `json:"type"` // request, response or event
+
+	Command   string      `json:"command,omitempty"`
+	Arguments interface{} `json:"arguments,omitempty"`
+
+	RequestSeq int             `json:"request_seq,omitempty"`
+	Success    bool            `json:"success,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Event      string          `json:"event,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// capabilities are the features of the adapter returned by initialize.
+type capabilities struct {
+	SupportsConfigurationDoneRequest  bool                        `json:"supportsConfigurationDoneRequest"`
+	SupportsFunctionBreakpoints       bool                        `json:"supportsFunctionBreakpoints"`
+	SupportsConditionalBreakpoints    bool                        `json:"supportsConditionalBreakpoints"`
+	SupportsHitConditionalBreakpoints bool                        `json:"supportsHitConditionalBreakpoints"`
+	SupportsSetVariable               bool                        `json:"supportsSetVariable"`
+	SupportsSetExpression             bool                        `json:"supportsSetExpression"`
+	SupportsTerminateRequest          bool                        `json:"supportsTerminateRequest"`
+	ExceptionBreakpointFilters        []exceptionBreakpointFilter `json:"exceptionBreakpointFilters"`
+}
+
+type exceptionBreakpointFilter struct {
+	Filter string `json:"filter"`
+	Label  string `json:"label"`
+}
+
+type source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+type sourceBreakpoint struct {
+	Line         int    `json:"line"`
+	Condition    string `json:"condition,omitempty"`
+	HitCondition string `json:"hitCondition,omitempty"`
+}
+
+type functionBreakpoint struct {
+	Name         string `json:"name"`
+	Condition    string `json:"condition,omitempty"`
+	HitCondition string `json:"hitCondition,omitempty"`
+}
+
+type stoppedEvent struct {
+	Reason      string `json:"reason"`
+	Description string `json:"description"`
+	ThreadID    int    `json:"threadId"`
+	Text        string `json:"text"`
+}
+
+type outputEvent struct {
+	Category string `json:"category"`
+	Output   string `json:"output"`
+}
+
+type thread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type threadsResponse struct {
+	Threads []thread `json:"threads"`
+}
+
+type stackFrame struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Source source `json:"source"`
+	Line   int    `json:"line"`
+}
+
+type stackTraceResponse struct {
+	StackFrames []stackFrame `json:"stackFrames"`
+}
+
+type scope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type scopesResponse struct {
+	Scopes []scope `json:"scopes"`
+}
+
+type variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type"`
+	EvaluateName       string `json:"evaluateName"`
+	VariablesReference int    `json:"variablesReference"`
+	NamedVariables     int    `json:"namedVariables"`
+	IndexedVariables   int    `json:"indexedVariables"`
+}
+
+type variablesResponse struct {
+	Variables []variable `json:"variables"`
+}
+
+type evaluateResponse struct {
+	Result             string `json:"result"`
+	Type               string `json:"type"`
+	VariablesReference int    `json:"variablesReference"`
+	NamedVariables     int    `json:"namedVariables"`
+	IndexedVariables   int    `json:"indexedVariables"`
+}
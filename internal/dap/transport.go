@@ -0,0 +1,184 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/textproto"
+	"strconv"
+	"sync"
+
+	"github.com/zyedidia/micro/v2/internal/shell"
+)
+
+// handler is called on the main loop with the response to a request.
+type handler func(message)
+
+// transport owns the connection to the debug adapter. Requests are written
+// from the main loop, while a reader goroutine reads the Content-Length
+// framed messages, matches responses to the request seq and posts them
+// back to the main loop.
+type transport struct {
+	conn io.ReadWriteCloser
+
+	mu      sync.Mutex
+	seq     int
+	pending map[int]handler
+	closed  bool
+
+	onEvent   func(message)
+	onRequest func(message) // reverse requests like runInTerminal
+	onClose   func(error)
+}
+
+func newTransport(conn io.ReadWriteCloser) *transport {
+	return &transport{
+		conn:    conn,
+		seq:     1,
+		pending: make(map[int]handler),
+	}
+}
+
+func (t *transport) start() {
+	go t.readLoop()
+}
+
+// send writes the request with the next seq. h is called with the response,
+// it can be nil.
+func (t *transport) send(cmd string, args interface{}, h handler) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return fmt.Errorf("%s: connection closed", cmd)
+	}
+
+	m := message{Seq: t.seq, Type: "request", Command: cmd, Arguments: args}
+	t.seq++
+	t.pending[m.Seq] = h
+
+	if err := t.write(m); err != nil {
+		delete(t.pending, m.Seq)
+		return fmt.Errorf("%s write. error: %w", cmd, err)
+	}
+	return nil
+}
+
+// reply answers the reverse request of the adapter.
+func (t *transport) reply(req message, err error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := message{Seq: t.seq, Type: "response", Command: req.Command, RequestSeq: req.Seq, Success: err == nil}
+	t.seq++
+	if err != nil {
+		m.Message = err.Error()
+	}
+	return t.write(m)
+}
+
+func (t *transport) write(m message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	log.Println("dap send:", string(b))
+	_, err = fmt.Fprintf(t.conn, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return err
+}
+
+// readMessage reads one message framed by the Content-Length header.
+func readMessage(r *bufio.Reader) (message, []byte, error) {
+	var m message
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return m, nil, err
+	}
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return m, nil, fmt.Errorf("Content-Length. error: %w", err)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return m, nil, err
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, nil, fmt.Errorf("message decode. error: %w", err)
+	}
+	return m, b, nil
+}
+
+func (t *transport) readLoop() {
+	var err error
+	r := bufio.NewReader(t.conn)
+
+	for {
+		var m message
+		var b []byte
+		m, b, err = readMessage(r)
+		if err != nil {
+			break
+		}
+		log.Println("dap recv:", string(b))
+		t.dispatch(m)
+	}
+
+	t.mu.Lock()
+	wasClosed := t.closed
+	t.closed = true
+	t.pending = make(map[int]handler)
+	t.mu.Unlock()
+
+	if wasClosed || err == io.EOF {
+		err = nil
+	}
+	log.Println("dap connection closed:", err)
+
+	if t.onClose != nil {
+		shell.Post(func() { t.onClose(err) })
+	}
+}
+
+func (t *transport) dispatch(m message) {
+	switch m.Type {
+	case "event":
+		if t.onEvent != nil {
+			shell.Post(func() { t.onEvent(m) })
+		}
+		return
+	case "request":
+		if t.onRequest != nil {
+			shell.Post(func() { t.onRequest(m) })
+		}
+		return
+	}
+
+	t.mu.Lock()
+	h, ok := t.pending[m.RequestSeq]
+	delete(t.pending, m.RequestSeq)
+	t.mu.Unlock()
+
+	if !ok {
+		log.Println("dap: unexpected response:", m.Command, m.RequestSeq)
+		return
+	}
+
+	if h != nil {
+		shell.Post(func() { h(m) })
+	}
+}
+
+// close closes the connection. The reader goroutine exits and calls onClose.
+func (t *transport) close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.mu.Unlock()
+
+	return t.conn.Close()
+}
@@ -0,0 +1,123 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/shell"
+)
+
+// writeMessage writes the JSON message framed like the adapter does.
+func writeMessage(t *testing.T, conn net.Conn, js string) {
+	_, err := fmt.Fprintf(conn, "Content-Length: %d\r\n\r\n%s", len(js), js)
+	assert.NoError(t, err)
+}
+
+// respond writes the successful response to the request with the body.
+func respond(t *testing.T, conn net.Conn, req message, body string) {
+	writeMessage(t, conn, fmt.Sprintf(`{"seq":0,"type":"response","request_seq":%d,"command":%q,"success":true,"body":%s}`, req.Seq, req.Command, body))
+}
+
+// event writes the event with the body.
+func event(t *testing.T, conn net.Conn, name, body string) {
+	writeMessage(t, conn, fmt.Sprintf(`{"seq":0,"type":"event","event":%q,"body":%s}`, name, body))
+}
+
+// adapterRequests reads requests from the adapter end of the pipe in the
+// background until it is closed, so that the client does not block on the
+// pipe.
+func adapterRequests(conn net.Conn) <-chan message {
+	c := make(chan message, 100)
+	go func() {
+		defer close(c)
+		r := bufio.NewReader(conn)
+		for {
+			m, _, err := readMessage(r)
+			if err != nil {
+				return
+			}
+			c <- m
+		}
+	}()
+	return c
+}
+
+// nextRequest returns the next request read by adapterRequests and checks
+// its command.
+func nextRequest(t *testing.T, c <-chan message, cmd string) message {
+	select {
+	case m := <-c:
+		assert.Equal(t, cmd, m.Command)
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for request " + cmd)
+	}
+	return message{}
+}
+
+// arguments returns the arguments of the request as JSON.
+func arguments(t *testing.T, m message) string {
+	b, err := json.Marshal(m.Arguments)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+// runJob runs the next callback posted to the main loop.
+func runJob(t *testing.T) {
+	select {
+	case f := <-shell.Jobs:
+		f.Function(f.Output, f.Args)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for posted callback")
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	// the length is in bytes
+	js := `{"seq":3,"type":"event","x":"é"}`
+	r := bufio.NewReader(strings.NewReader(fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(js), js) +
+		"Content-Length: 2\r\n\r\n{}"))
+	m, _, err := readMessage(r)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, m.Seq)
+	assert.Equal(t, "event", m.Type)
+	_, _, err = readMessage(r)
+	assert.NoError(t, err)
+	_, _, err = readMessage(r)
+	assert.Error(t, err)
+}
+
+func TestTransportCorrelation(t *testing.T) {
+	ide, adapter := net.Pipe()
+	tr := newTransport(ide)
+	var closed bool
+	tr.onClose = func(err error) { closed = err == nil }
+	tr.start()
+	reqs := adapterRequests(adapter)
+
+	var first, second message
+	assert.NoError(t, tr.send("threads", nil, func(m message) { first = m }))
+	assert.NoError(t, tr.send("evaluate", map[string]string{"expression": "1+1"}, func(m message) { second = m }))
+	r1 := nextRequest(t, reqs, "threads")
+	r2 := nextRequest(t, reqs, "evaluate")
+	assert.Equal(t, `{"expression":"1+1"}`, arguments(t, r2))
+
+	// responses out of order
+	respond(t, adapter, r2, `{"result":"2"}`)
+	runJob(t)
+	respond(t, adapter, r1, `{}`)
+	runJob(t)
+	assert.Equal(t, "evaluate", second.Command)
+	assert.JSONEq(t, `{"result":"2"}`, string(second.Body))
+	assert.Equal(t, "threads", first.Command)
+
+	adapter.Close()
+	runJob(t)
+	assert.True(t, closed)
+}
@@ -0,0 +1,304 @@
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// newProperty converts the variable of the adapter. The variables
+// reference of a structured value is kept to fetch its children.
+func (c *Client) newProperty(name, fullName, value, typ string, ref, named, indexed int) *debugger.Property {
+	// Go strings are quoted by Delve, Summary quotes them again
+	if typ == "string" {
+		if s, err := strconv.Unquote(value); err == nil {
+			value = s
+		}
+	}
+	if fullName == "" {
+		fullName = name
+	}
+	p := &debugger.Property{
+		Name:     name,
+		FullName: fullName,
+		Type:     typ,
+		Value:    value,
+	}
+	if ref > 0 {
+		// the number of children may be unknown until they are fetched
+		p.NumChildren = named + indexed
+		if p.NumChildren == 0 {
+			p.NumChildren = 1
+		}
+		if c.varRefs == nil {
+			c.varRefs = make(map[*debugger.Property]int)
+		}
+		c.varRefs[p] = ref
+	}
+	return p
+}
+
+// Contexts returns the scopes of the selected stack frame.
+func (c *Client) Contexts() []*debugger.Context {
+	return c.contexts
+}
+
+// refreshVariables fetches the scopes of the selected frame. Scopes and
+// variables which were expanded stay expanded.
+func (c *Client) refreshVariables() {
+	id, ok := c.frameID(c.depth)
+	if !ok {
+		return
+	}
+
+	expanded := make(map[string]bool)
+	for _, ctx := range c.contexts {
+		if ctx.Expanded {
+			expanded[ctx.Name] = true
+		}
+		collectExpanded(ctx.Name+"\x00", ctx.Properties, expanded)
+	}
+
+	c.request("scopes", map[string]interface{}{"frameId": id}, func(m message) {
+		var resp scopesResponse
+		if err := json.Unmarshal(m.Body, &resp); err != nil {
+			log.Println(err)
+			return
+		}
+		c.contexts = nil
+		c.scopeRefs = make(map[*debugger.Context]int)
+		for i, s := range resp.Scopes {
+			ctx := &debugger.Context{
+				ID:   i,
+				Name: s.Name,
+				// the first scope is usually locals
+				Expanded: i == 0 && len(expanded) == 0 || expanded[s.Name],
+			}
+			c.contexts = append(c.contexts, ctx)
+			c.scopeRefs[ctx] = s.VariablesReference
+			if ctx.Expanded {
+				c.getScope(ctx, expanded)
+			}
+		}
+		c.Editor.VariablesChanged()
+	})
+}
+
+func collectExpanded(prefix string, props []*debugger.Property, expanded map[string]bool) {
+	for _, p := range props {
+		if p.Expanded {
+			expanded[prefix+p.FullName] = true
+			collectExpanded(prefix, p.Children, expanded)
+		}
+	}
+}
+
+// getScope fetches the variables of the scope and expands the ones which
+// were expanded.
+func (c *Client) getScope(ctx *debugger.Context, expanded map[string]bool) {
+	c.variables(c.scopeRefs[ctx], func(props []*debugger.Property) {
+		ctx.Properties = props
+		c.restoreExpanded(ctx.Name+"\x00", props, expanded)
+		c.Editor.VariablesChanged()
+	})
+}
+
+func (c *Client) restoreExpanded(prefix string, props []*debugger.Property, expanded map[string]bool) {
+	for _, p := range props {
+		if expanded[prefix+p.FullName] && c.varRefs[p] > 0 {
+			p.Expanded = true
+			c.loadChildren(p, func() { c.restoreExpanded(prefix, p.Children, expanded) })
+		}
+	}
+}
+
+// variables fetches the variables of the reference.
+func (c *Client) variables(ref int, done func([]*debugger.Property)) {
+	c.request("variables", map[string]interface{}{"variablesReference": ref}, func(m message) {
+		var resp variablesResponse
+		if err := json.Unmarshal(m.Body, &resp); err != nil {
+			log.Println(err)
+			return
+		}
+		var props []*debugger.Property
+		for _, v := range resp.Variables {
+			props = append(props, c.newProperty(v.Name, v.EvaluateName, v.Value, v.Type,
+				v.VariablesReference, v.NamedVariables, v.IndexedVariables))
+		}
+		done(props)
+	})
+}
+
+// loadChildren fetches all children of the property.
+func (c *Client) loadChildren(p *debugger.Property, done func()) {
+	c.variables(c.varRefs[p], func(props []*debugger.Property) {
+		p.Children = props
+		p.NumChildren = len(props)
+		if done != nil {
+			done()
+		}
+		c.Editor.VariablesChanged()
+	})
+}
+
+// ToggleContext expands or collapses the scope. Expanded scopes are fetched
+// from the adapter.
+func (c *Client) ToggleContext(ctx *debugger.Context) {
+	ctx.Expanded = !ctx.Expanded
+	if ctx.Expanded && c.status == "break" {
+		c.getScope(ctx, nil)
+	}
+	c.Editor.VariablesChanged()
+}
+
+// ToggleProperty expands or collapses the property. Children are fetched
+// when the property is expanded first time.
+func (c *Client) ToggleProperty(p *debugger.Property) {
+	p.Expanded = !p.Expanded
+	if p.Expanded && len(p.Children) == 0 && c.varRefs[p] > 0 && c.status == "break" {
+		c.loadChildren(p, nil)
+	}
+	c.Editor.VariablesChanged()
+}
+
+// LoadMore does nothing, children are fetched at once.
+func (c *Client) LoadMore(p *debugger.Property) {}
+
+// Eval evaluates the expression in the selected stack frame.
+func (c *Client) Eval(expr string, done func(*debugger.Property, error)) error {
+	return c.evaluate(expr, "repl", done)
+}
+
+// Inspect is like Eval for the values of expressions under the mouse.
+func (c *Client) Inspect(expr string, done func(*debugger.Property, error)) error {
+	return c.evaluate(expr, "hover", done)
+}
+
+func (c *Client) evaluate(expr, context string, done func(*debugger.Property, error)) error {
+	id, ok := c.frameID(c.depth)
+	if !ok {
+		return fmt.Errorf("eval: the debugger is not stopped at a break")
+	}
+
+	args := map[string]interface{}{"expression": expr, "frameId": id, "context": context}
+	return c.conn.send("evaluate", args, func(m message) {
+		if !m.Success {
+			done(nil, fmt.Errorf("%s", m.Message))
+			return
+		}
+		var resp evaluateResponse
+		if err := json.Unmarshal(m.Body, &resp); err != nil {
+			done(nil, err)
+			return
+		}
+		done(c.newProperty(expr, expr, resp.Result, resp.Type,
+			resp.VariablesReference, resp.NamedVariables, resp.IndexedVariables), nil)
+	})
+}
+
+// SetVariable sets the value of the variable with setExpression or, for
+// variables of a scope, with setVariable and refreshes the variables.
+func (c *Client) SetVariable(a debugger.Assignment, done func(error)) error {
+	id, ok := c.frameID(a.Depth)
+	if !ok {
+		return fmt.Errorf("set: the debugger is not stopped at a break")
+	}
+
+	handle := func(m message) {
+		if !m.Success {
+			done(fmt.Errorf("set %s: %s", a.Name, m.Message))
+			return
+		}
+		c.refreshVariables()
+		c.evalWatches()
+		done(nil)
+	}
+
+	if c.caps.SupportsSetExpression {
+		args := map[string]interface{}{"expression": a.Name, "value": a.Value, "frameId": id}
+		return c.conn.send("setExpression", args, handle)
+	}
+
+	if !c.caps.SupportsSetVariable {
+		return fmt.Errorf("set: not supported by the debug adapter")
+	}
+	if strings.ContainsAny(a.Name, ".[") {
+		return fmt.Errorf("set: the debug adapter sets variables of scopes only")
+	}
+	if a.Depth != c.depth || a.Context < 0 || a.Context >= len(c.contexts) {
+		return fmt.Errorf("set: no scope %d in the selected frame", a.Context)
+	}
+	args := map[string]interface{}{
+		"variablesReference": c.scopeRefs[c.contexts[a.Context]],
+		"name":               a.Name,
+		"value":              a.Value,
+	}
+	return c.conn.send("setVariable", args, handle)
+}
+
+// Watches returns the watch expressions.
+func (c *Client) Watches() []*debugger.Watch {
+	return c.watches
+}
+
+// SetWatches replaces watch expressions.
+func (c *Client) SetWatches(exprs []string) {
+	c.watches = nil
+	for _, e := range exprs {
+		c.watches = append(c.watches, &debugger.Watch{Expression: e})
+	}
+}
+
+// WatchExpressions returns the watch expressions to be saved.
+func (c *Client) WatchExpressions() []string {
+	var exprs []string
+	for _, w := range c.watches {
+		exprs = append(exprs, w.Expression)
+	}
+	return exprs
+}
+
+// AddWatch adds the watch expression and evaluates it at a break.
+func (c *Client) AddWatch(expr string) {
+	w := &debugger.Watch{Expression: expr}
+	c.watches = append(c.watches, w)
+	if c.status == "break" {
+		c.evalWatch(w)
+	}
+	c.Editor.WatchesChanged()
+}
+
+// RemoveWatch removes the n-th watch expression.
+func (c *Client) RemoveWatch(n int) error {
+	if n < 1 || n > len(c.watches) {
+		return fmt.Errorf("no watch %d", n)
+	}
+	c.watches = append(c.watches[:n-1], c.watches[n:]...)
+	c.Editor.WatchesChanged()
+	return nil
+}
+
+func (c *Client) evalWatches() {
+	for _, w := range c.watches {
+		c.evalWatch(w)
+	}
+}
+
+func (c *Client) evalWatch(w *debugger.Watch) {
+	err := c.evaluate(w.Expression, "watch", func(p *debugger.Property, err error) {
+		prev := w.Value
+		w.Value, w.Error = p, ""
+		if err != nil {
+			w.Error = err.Error()
+		}
+		w.Changed = prev != nil && !debugger.SameValue(prev, w.Value)
+		c.Editor.WatchesChanged()
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,71 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Assignment is a value set to a variable of the debugged program.
+type Assignment struct {
+	Name    string // full name of the variable, e.g. $a['b']->c
+	Context int    // variable context ID, 0 for locals
+	Depth   int    // stack frame level
+	Type    string // data type of Value, if empty Value is an expression of the language
+	Value   string
+}
+
+// ParseAssignment parses arguments of the set command:
+//
+//	[-c CONTEXT] [-d DEPTH] [-t TYPE] NAME [=] VALUE...
+//
+// The depth defaults to depth. Without a type the value is evaluated by the
+// backend as an expression, e.g. "abc" or [1, 2].
+func ParseAssignment(args []string, depth int) (Assignment, error) {
+	a := Assignment{Depth: depth}
+
+	for len(args) > 1 && strings.HasPrefix(args[0], "-") {
+		var err error
+		switch args[0] {
+		case "-c":
+			a.Context, err = strconv.Atoi(args[1])
+		case "-d":
+			a.Depth, err = strconv.Atoi(args[1])
+		case "-t":
+			a.Type = args[1]
+		default:
+			return a, fmt.Errorf("set: unknown option %s", args[0])
+		}
+		if err != nil {
+			return a, fmt.Errorf("set: invalid %s %q", args[0], args[1])
+		}
+		args = args[2:]
+	}
+
+	if len(args) == 0 {
+		return a, fmt.Errorf("set: variable name expected")
+	}
+	a.Name = args[0]
+	args = args[1:]
+	if len(args) > 0 && args[0] == "=" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return a, fmt.Errorf("set: value expected")
+	}
+	a.Value = strings.Join(args, " ")
+
+	return a, nil
+}
+
+// AssignProperty returns the assignment of the value to the property in its
+// context and stack frame. Scalar values are sent with the type of the
+// property, other values are evaluated as expressions.
+func AssignProperty(p *Property, value string) Assignment {
+	a := Assignment{Name: p.FullName, Context: p.Context, Depth: p.Depth, Value: value}
+	switch p.Type {
+	case "bool", "int", "float", "string":
+		a.Type = p.Type
+	}
+	return a
+}
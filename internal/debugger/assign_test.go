@@ -0,0 +1,27 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAssignment(t *testing.T) {
+	a, err := ParseAssignment([]string{"$a['k']", "=", "[1,", "2]"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, Assignment{Name: "$a['k']", Depth: 1, Value: "[1, 2]"}, a)
+
+	a, err = ParseAssignment([]string{"-c", "1", "-d", "0", "-t", "string", "$_GET['q']", "a", "b"}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, Assignment{Name: "$_GET['q']", Context: 1, Type: "string", Value: "a b"}, a)
+
+	_, err = ParseAssignment([]string{"$a"}, 0)
+	assert.EqualError(t, err, "set: value expected")
+	_, err = ParseAssignment([]string{"-d", "x", "$a", "1"}, 0)
+	assert.EqualError(t, err, `set: invalid -d "x"`)
+
+	p := &Property{FullName: "$n", Type: "int", Depth: 2}
+	assert.Equal(t, Assignment{Name: "$n", Depth: 2, Type: "int", Value: "5"}, AssignProperty(p, "5"))
+	p.Type = "array"
+	assert.Equal(t, "", AssignProperty(p, "[]").Type)
+}
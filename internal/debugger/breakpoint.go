@@ -0,0 +1,125 @@
+package debugger
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BreakpointTypes are the breakpoint types accepted by ParseBreakpoint.
+var BreakpointTypes = []string{"line", "cond", "call", "return", "exception"}
+
+// HitConditions are the hit condition operators.
+var HitConditions = []string{">=", "==", "%"}
+
+// Breakpoint is a breakpoint created from the command line. Plain line
+// breakpoints toggled in the gutter are kept separately by the backends.
+type Breakpoint struct {
+	Type         string // line, conditional, call, return or exception
+	File         string // local file of line and conditional breakpoints
+	Line         int
+	Expression   string // condition of conditional breakpoints
+	Function     string // function of call and return breakpoints
+	Exception    string // exception class of the engine or exception filter of the adapter, * for all
+	HitCondition string // one of HitConditions
+	HitValue     int
+}
+
+var hitRegexp = regexp.MustCompile(`^hit(>=|==|%)(\d+)$`)
+var locRegexp = regexp.MustCompile(`^(?:(.*):)?(\d+)$`)
+
+// ParseBreakpoint parses breakpoint arguments:
+//
+//	line [[FILE:]LINE] [hit>=N]
+//	cond [[FILE:]LINE] if EXPRESSION... [hit>=N]
+//	call|return FUNCTION [hit>=N]
+//	exception CLASS|FILTER [hit>=N]
+//
+// The file and line default to fname and line. The condition follows if,
+// so that it can start with a number. The hit condition can be one of
+// hit>=N, hit==N or hit%N.
+func ParseBreakpoint(args []string, fname string, line int) (Breakpoint, error) {
+	var bp Breakpoint
+
+	if len(args) == 0 {
+		return bp, fmt.Errorf("breakpoint type expected: %s", strings.Join(BreakpointTypes, ", "))
+	}
+
+	t := args[0]
+	args = args[1:]
+
+	if len(args) > 0 {
+		if m := hitRegexp.FindStringSubmatch(args[len(args)-1]); m != nil {
+			bp.HitCondition = m[1]
+			bp.HitValue, _ = strconv.Atoi(m[2])
+			args = args[:len(args)-1]
+		}
+	}
+
+	switch t {
+	case "line", "cond":
+		bp.Type = "line"
+		bp.File = fname
+		bp.Line = line
+		if len(args) > 0 && args[0] != "if" {
+			m := locRegexp.FindStringSubmatch(args[0])
+			if m == nil {
+				return bp, fmt.Errorf("%s breakpoint: [FILE:]LINE expected, got %q", t, args[0])
+			}
+			if m[1] != "" {
+				bp.File = m[1]
+			}
+			bp.Line, _ = strconv.Atoi(m[2])
+			args = args[1:]
+		}
+		if bp.File == "" {
+			return bp, fmt.Errorf("%s breakpoint: file expected", t)
+		}
+		if t == "cond" {
+			bp.Type = "conditional"
+			if len(args) > 0 && args[0] == "if" {
+				bp.Expression = strings.Join(args[1:], " ")
+			}
+			if bp.Expression == "" {
+				return bp, fmt.Errorf("cond breakpoint: if EXPRESSION expected")
+			}
+		} else if len(args) > 0 {
+			return bp, fmt.Errorf("line breakpoint: unexpected %q", strings.Join(args, " "))
+		}
+	case "call", "return":
+		bp.Type = t
+		if len(args) != 1 {
+			return bp, fmt.Errorf("%s breakpoint: function name expected", t)
+		}
+		bp.Function = args[0]
+	case "exception":
+		bp.Type = t
+		if len(args) != 1 {
+			return bp, fmt.Errorf("exception breakpoint: class name or filter expected")
+		}
+		bp.Exception = args[0]
+	default:
+		return bp, fmt.Errorf("unknown breakpoint type %q", t)
+	}
+
+	return bp, nil
+}
+
+func (bp *Breakpoint) String() string {
+	var s string
+	switch bp.Type {
+	case "line":
+		s = fmt.Sprintf("line %s:%d", bp.File, bp.Line)
+	case "conditional":
+		s = fmt.Sprintf("cond %s:%d if %s", bp.File, bp.Line, bp.Expression)
+	case "call", "return":
+		s = bp.Type + " " + bp.Function
+	case "exception":
+		s = "exception " + bp.Exception
+	}
+	if bp.HitCondition != "" {
+		s += fmt.Sprintf(" hit%s%d", bp.HitCondition, bp.HitValue)
+	}
+	return s
+}
@@ -0,0 +1,38 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBreakpoint(t *testing.T) {
+	bp, err := ParseBreakpoint([]string{"cond", "src/a.php:12", "if", "$x", ">", "5", "hit>=3"}, "/p/b.php", 7)
+	assert.NoError(t, err)
+	assert.Equal(t, Breakpoint{Type: "conditional", File: "src/a.php", Line: 12, Expression: "$x > 5", HitCondition: ">=", HitValue: 3}, bp)
+	assert.Equal(t, "cond src/a.php:12 if $x > 5 hit>=3", bp.String())
+
+	// a condition starting with a number is not a line
+	bp, err = ParseBreakpoint([]string{"cond", "if", "5", "<", "$x"}, "/p/b.php", 7)
+	assert.NoError(t, err)
+	assert.Equal(t, Breakpoint{Type: "conditional", File: "/p/b.php", Line: 7, Expression: "5 < $x"}, bp)
+
+	bp, err = ParseBreakpoint([]string{"line"}, "/p/b.php", 7)
+	assert.NoError(t, err)
+	assert.Equal(t, Breakpoint{Type: "line", File: "/p/b.php", Line: 7}, bp)
+
+	bp, err = ParseBreakpoint([]string{"exception", "*", "hit%2"}, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "exception * hit%2", bp.String())
+
+	_, err = ParseBreakpoint([]string{"cond", "12"}, "/p/b.php", 7)
+	assert.Error(t, err)
+	_, err = ParseBreakpoint([]string{"cond", "12", "$x"}, "/p/b.php", 7)
+	assert.EqualError(t, err, "cond breakpoint: if EXPRESSION expected")
+	_, err = ParseBreakpoint([]string{"line", "$x"}, "/p/b.php", 7)
+	assert.Error(t, err)
+	_, err = ParseBreakpoint([]string{"call"}, "", 0)
+	assert.Error(t, err)
+	_, err = ParseBreakpoint([]string{"watch", "$x"}, "", 0)
+	assert.Error(t, err)
+}
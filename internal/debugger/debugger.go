@@ -0,0 +1,74 @@
+// Package debugger is the language independent layer of the debugger. The
+// gutter, the panes and the key bindings of the editor drive a Backend: the
+// xdebug client for PHP or the DAP client for debug adapters like Delve.
+package debugger
+
+import (
+	"errors"
+	"io"
+)
+
+// Editor is a callback interface for editor automation.
+type Editor interface {
+	OpenCmd([]string)                // open FILE
+	GotoCmd([]string)                // goto LINE
+	Message(msg ...interface{})      // shows message on status bar
+	Error(msg ...interface{})        // shows error message on status bar
+	VariablesChanged()               // variables returned by Contexts or children of properties are updated
+	WatchesChanged()                 // watches returned by Watches are updated
+	StackChanged()                   // stack returned by Stack or the selected frame is changed
+	SessionsChanged()                // sessions returned by Sessions or the current session are changed
+	Output(session int, text string) // script output of the session, stdout and stderr
+	LocationChanged()                // break returned by Location or values returned by InlineValues are changed
+	OpenSource(string, string, int)  // open URI read-only with SOURCE at LINE, for engine code without local file
+}
+
+// ErrNotVariable is returned by Backend.Inspect for expressions it does not
+// evaluate.
+var ErrNotVariable = errors.New("not a variable")
+
+// Backend is a debugger client. Methods are called on the main loop and
+// the backend reports changes with the callbacks of its Editor.
+type Backend interface {
+	// ProcessCommand runs the session commands like start, stop, s, n, so,
+	// c and break.
+	ProcessCommand(args []string) error
+	Status() string // status for the statusline, empty if not debugging
+	Shutdown()      // closes connections when the editor quits
+
+	Sessions() []SessionInfo
+	SelectSession(id int) error
+	DetachSession(id int) error
+	StopSession(id int) error
+
+	Location() (string, int) // file URI and line of the break
+	LocalPath(uri string) (string, error)
+	LocalFile(uri string) (string, error)
+	Stack() []Frame
+	Depth() int
+	SelectFrame(level int) error
+	RunTo(fname string, line int) error
+
+	Contexts() []*Context
+	ToggleContext(c *Context)
+	ToggleProperty(p *Property)
+	LoadMore(p *Property)
+	Eval(expr string, done func(*Property, error)) error
+	// Inspect is like Eval without running code of the script, e.g. for
+	// the expression under the mouse. Expressions which cannot be read
+	// without running code return ErrNotVariable.
+	Inspect(expr string, done func(*Property, error)) error
+	InlineValues() map[int][]*Property
+	SetVariable(a Assignment, done func(error)) error
+
+	SetBreakpoints(fname string, lines []int)
+	AddBreakpoint(bp Breakpoint) error
+	RemoveBreakpoint(n int) error
+	ListBreakpoints(w io.Writer)
+
+	Watches() []*Watch
+	SetWatches(exprs []string)
+	WatchExpressions() []string
+	AddWatch(expr string)
+	RemoveWatch(n int) error
+}
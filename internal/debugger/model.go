@@ -0,0 +1,106 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SessionInfo describes a debug session for the session list.
+type SessionInfo struct {
+	ID       int
+	IDEKey   string
+	File     string // file URI of the script or program
+	Language string // language name and version of the engine or the adapter name
+	Status   string
+	Current  bool
+}
+
+// SessionCounter numbers debug sessions. Backends sharing a counter give
+// their sessions distinct IDs, so that their outputs are kept apart.
+type SessionCounter struct {
+	last int
+}
+
+// Next returns the ID of a new session.
+func (c *SessionCounter) Next() int {
+	c.last++
+	return c.last
+}
+
+// Frame is a stack frame of the debugged program.
+type Frame struct {
+	Level int
+	File  string // file URI of the backend
+	Line  int
+	Where string
+}
+
+// Context is a variable context such as Locals or Superglobals.
+type Context struct {
+	ID         int
+	Name       string
+	Properties []*Property
+	Expanded   bool
+}
+
+// Property is a variable of the debugged program. Children can be loaded
+// lazily page by page.
+type Property struct {
+	Name        string
+	FullName    string
+	Type        string
+	ClassName   string
+	Value       string
+	NumChildren int
+	Children    []*Property
+	Expanded    bool
+
+	Context int // ID of the variable context
+	Depth   int // level of the stack frame
+	Pages   int // pages of children loaded by backends fetching them page by page
+}
+
+// HasMore returns true if not all children of the property are loaded.
+func (p *Property) HasMore() bool {
+	return len(p.Children) < p.NumChildren
+}
+
+// Summary returns the type and the value of the property in one line.
+func (p *Property) Summary() string {
+	switch p.Type {
+	case "array":
+		return fmt.Sprintf("array(%d)", p.NumChildren)
+	case "object":
+		return fmt.Sprintf("%s{%d}", p.ClassName, p.NumChildren)
+	case "string":
+		return "string " + strconv.Quote(p.Value)
+	case "null", "uninitialized":
+		return p.Type
+	}
+	return p.Type + " " + p.Value
+}
+
+// SameValue reports whether the properties have the same value. Children
+// are compared as far as they are loaded in both.
+func SameValue(a, b *Property) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Type != b.Type || a.ClassName != b.ClassName || a.Value != b.Value || a.NumChildren != b.NumChildren {
+		return false
+	}
+	for i := 0; i < len(a.Children) && i < len(b.Children); i++ {
+		if a.Children[i].Name != b.Children[i].Name || !SameValue(a.Children[i], b.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Watch is an expression evaluated after every step or break.
+type Watch struct {
+	Expression string
+	Value      *Property // nil if not evaluated or failed
+	Error      string    // evaluation error
+	Changed    bool      // value changed since the previous evaluation
+}
@@ -0,0 +1,147 @@
+package debugger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ProjectDir is the directory relative to the project root where debugger
+// files of the project are stored.
+const ProjectDir = ".micro"
+
+// ProjectRoot returns the nearest directory from dir up containing .git or
+// .micro directory. If there is no such directory it returns dir.
+func ProjectRoot(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+
+	for d := dir; ; {
+		for _, name := range []string{ProjectDir, ".git"} {
+			if _, err := os.Stat(filepath.Join(d, name)); err == nil {
+				return d
+			}
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
+
+func breakpointsFile(root string) string {
+	return filepath.Join(root, ProjectDir, "breakpoints.yaml")
+}
+
+// LoadBreakpoints reads breakpoints of the project. The result maps absolute
+// file paths to sorted 0-based line numbers.
+func LoadBreakpoints(root string) (map[string][]int, error) {
+	bps := make(map[string][]int)
+
+	b, err := ioutil.ReadFile(breakpointsFile(root))
+	if err != nil && os.IsNotExist(err) {
+		return bps, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("breakpoints read. error: %w", err)
+	}
+
+	var saved map[string][]int
+	if err := yaml.Unmarshal(b, &saved); err != nil {
+		return nil, fmt.Errorf("breakpoints decode. error: %w", err)
+	}
+
+	for fname, lines := range saved {
+		if !filepath.IsAbs(fname) {
+			fname = filepath.Join(root, fname)
+		}
+		for _, l := range lines {
+			if l > 0 {
+				bps[fname] = append(bps[fname], l-1)
+			}
+		}
+		sort.Ints(bps[fname])
+	}
+
+	return bps, nil
+}
+
+// SaveBreakpoints writes breakpoints of the project. Paths inside the project
+// are stored relative to root and lines are stored 1-based.
+func SaveBreakpoints(root string, bps map[string][]int) error {
+	saved := make(map[string][]int)
+	for fname, lines := range bps {
+		if len(lines) == 0 {
+			continue
+		}
+		if rel, err := filepath.Rel(root, fname); err == nil && !strings.HasPrefix(rel, "..") {
+			fname = rel
+		}
+		for _, l := range lines {
+			saved[fname] = append(saved[fname], l+1)
+		}
+	}
+
+	b, err := yaml.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("breakpoints encode. error: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, ProjectDir), os.ModePerm); err != nil {
+		return fmt.Errorf("breakpoints dir. error: %w", err)
+	}
+
+	if err := ioutil.WriteFile(breakpointsFile(root), b, 0644); err != nil {
+		return fmt.Errorf("breakpoints write. error: %w", err)
+	}
+
+	return nil
+}
+
+func watchesFile(root string) string {
+	return filepath.Join(root, ProjectDir, "watches.yaml")
+}
+
+// LoadWatches reads watch expressions of the project.
+func LoadWatches(root string) ([]string, error) {
+	b, err := ioutil.ReadFile(watchesFile(root))
+	if err != nil && os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("watches read. error: %w", err)
+	}
+
+	var watches []string
+	if err := yaml.Unmarshal(b, &watches); err != nil {
+		return nil, fmt.Errorf("watches decode. error: %w", err)
+	}
+
+	return watches, nil
+}
+
+// SaveWatches writes watch expressions of the project.
+func SaveWatches(root string, watches []string) error {
+	b, err := yaml.Marshal(watches)
+	if err != nil {
+		return fmt.Errorf("watches encode. error: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, ProjectDir), os.ModePerm); err != nil {
+		return fmt.Errorf("watches dir. error: %w", err)
+	}
+
+	if err := ioutil.WriteFile(watchesFile(root), b, 0644); err != nil {
+		return fmt.Errorf("watches write. error: %w", err)
+	}
+
+	return nil
+}
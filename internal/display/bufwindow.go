@@ -178,7 +178,8 @@ func (w *BufWindow) Relocate() bool {
 func (w *BufWindow) LocFromVisual(svloc buffer.Loc) buffer.Loc {
 	b := w.Buf
 
-	hasMessage := len(b.Messages) > 0
+	hasMessage := len(b.Messages) > 0 || b.HasBreakpoints()
+	hasCosts := b.HasLineCosts()
 	bufHeight := w.Height
 	if w.drawStatus {
 		bufHeight--
@@ -208,6 +209,9 @@ func (w *BufWindow) LocFromVisual(svloc buffer.Loc) buffer.Loc {
 		if hasMessage {
 			vloc.X += 2
 		}
+		if hasCosts {
+			vloc.X += costGutterWidth
+		}
 		if b.Settings["diffgutter"].(bool) {
 			vloc.X++
 		}
@@ -290,7 +294,7 @@ func (w *BufWindow) LocFromVisual(svloc buffer.Loc) buffer.Loc {
 	return buffer.Loc{}
 }
 
-func (w *BufWindow) drawGutter(vloc *buffer.Loc, bloc *buffer.Loc) {
+func (w *BufWindow) drawGutter(softwrapped bool, vloc *buffer.Loc, bloc *buffer.Loc) {
 	char := ' '
 	s := config.DefStyle
 	for _, m := range w.Buf.Messages {
@@ -300,12 +304,45 @@ func (w *BufWindow) drawGutter(vloc *buffer.Loc, bloc *buffer.Loc) {
 			break
 		}
 	}
-	screen.SetContent(w.X+vloc.X, w.Y+vloc.Y, char, nil, s)
+
+	bchar, bs := char, s
+	if !softwrapped && w.Buf.HasBreakpoint(bloc.Y) {
+		bchar = '*'
+		bs = config.DefStyle
+		if style, ok := config.Colorscheme["gutter-breakpoint"]; ok {
+			bs = style
+		} else if style, ok := config.Colorscheme["gutter-error"]; ok {
+			bs = style
+		}
+	}
+
+	screen.SetContent(w.X+vloc.X, w.Y+vloc.Y, bchar, nil, bs)
 	vloc.X++
 	screen.SetContent(w.X+vloc.X, w.Y+vloc.Y, char, nil, s)
 	vloc.X++
 }
 
+// costGutterWidth is the width of the gutter with the costs of lines
+const costGutterWidth = 7
+
+// drawCostGutter draws the cost of the line, e.g. its share of time in a
+// profile of the debugger, right aligned
+func (w *BufWindow) drawCostGutter(style tcell.Style, softwrapped bool, vloc *buffer.Loc, bloc *buffer.Loc) {
+	var cost []rune
+	if !softwrapped {
+		cost = []rune(w.Buf.LineCost(bloc.Y))
+	}
+	style = inlineValueStyle(style)
+	for i := 0; i < costGutterWidth; i++ {
+		r := ' '
+		if j := i - (costGutterWidth - 1 - len(cost)); j >= 0 && j < len(cost) {
+			r = cost[j]
+		}
+		screen.SetContent(w.X+vloc.X, w.Y+vloc.Y, r, nil, style)
+		vloc.X++
+	}
+}
+
 func (w *BufWindow) drawDiffGutter(backgroundStyle tcell.Style, softwrapped bool, vloc *buffer.Loc, bloc *buffer.Loc) {
 	symbol := ' '
 	styleName := ""
@@ -392,7 +429,8 @@ func (w *BufWindow) displayBuffer() {
 		return
 	}
 
-	hasMessage := len(b.Messages) > 0
+	hasMessage := len(b.Messages) > 0 || b.HasBreakpoints()
+	hasCosts := b.HasLineCosts()
 	bufHeight := w.Height
 	if w.drawStatus {
 		bufHeight--
@@ -497,7 +535,11 @@ func (w *BufWindow) displayBuffer() {
 		}
 
 		if hasMessage {
-			w.drawGutter(&vloc, &bloc)
+			w.drawGutter(false, &vloc, &bloc)
+		}
+
+		if hasCosts {
+			w.drawCostGutter(s, false, &vloc, &bloc)
 		}
 
 		if b.Settings["diffgutter"].(bool) {
@@ -525,11 +567,13 @@ func (w *BufWindow) displayBuffer() {
 				// over cursor-line and color-column
 				dontOverrideBackground := origBg != defBg
 
+				selected := false
 				for _, c := range cursors {
 					if c.HasSelection() &&
 						(bloc.GreaterEqual(c.CurSelection[0]) && bloc.LessThan(c.CurSelection[1]) ||
 							bloc.LessThan(c.CurSelection[0]) && bloc.GreaterEqual(c.CurSelection[1])) {
 						// The current character is selected
+						selected = true
 						style = config.DefStyle.Reverse(true)
 
 						if s, ok := config.Colorscheme["selection"]; ok {
@@ -546,6 +590,11 @@ func (w *BufWindow) displayBuffer() {
 					}
 				}
 
+				// the debugger line takes precedence over syntax highlighting
+				if !selected && b.IsDebugLine(bloc.Y) {
+					style = debugLineStyle(style)
+				}
+
 				for _, m := range b.Messages {
 					if bloc.GreaterEqual(m.Start) && bloc.LessThan(m.End) ||
 						bloc.LessThan(m.End) && bloc.GreaterEqual(m.Start) {
@@ -637,7 +686,10 @@ func (w *BufWindow) displayBuffer() {
 					}
 					vloc.X = 0
 					if hasMessage {
-						w.drawGutter(&vloc, &bloc)
+						w.drawGutter(true, &vloc, &bloc)
+					}
+					if hasCosts {
+						w.drawCostGutter(lineNumStyle, true, &vloc, &bloc)
 					}
 					if b.Settings["diffgutter"].(bool) {
 						w.drawDiffGutter(lineNumStyle, true, &vloc, &bloc)
@@ -661,6 +713,12 @@ func (w *BufWindow) displayBuffer() {
 				}
 			}
 		}
+		if b.IsDebugLine(bloc.Y) {
+			style = debugLineStyle(style)
+		}
+
+		// values of the debugger are shown after the end of the line
+		inline := []rune(b.InlineValue(bloc.Y))
 		for i := vloc.X; i < bufWidth; i++ {
 			curStyle := style
 			if s, ok := config.Colorscheme["color-column"]; ok {
@@ -669,7 +727,12 @@ func (w *BufWindow) displayBuffer() {
 					curStyle = style.Background(fg)
 				}
 			}
-			screen.SetContent(i+w.X, vloc.Y+w.Y, ' ', nil, curStyle)
+			r := ' '
+			if j := i - vloc.X - 2; j >= 0 && j < len(inline) {
+				r = inline[j]
+				curStyle = inlineValueStyle(curStyle)
+			}
+			screen.SetContent(i+w.X, vloc.Y+w.Y, r, nil, curStyle)
 		}
 
 		if vloc.X != bufWidth {
@@ -685,6 +748,24 @@ func (w *BufWindow) displayBuffer() {
 	}
 }
 
+// debugLineStyle returns the style of the line the debugger is stopped at
+func debugLineStyle(style tcell.Style) tcell.Style {
+	if s, ok := config.Colorscheme["debug-line"]; ok {
+		fg, _, _ := s.Decompose()
+		return style.Background(fg)
+	}
+	return style.Reverse(true)
+}
+
+// inlineValueStyle returns the dimmed style of values shown by the debugger
+func inlineValueStyle(style tcell.Style) tcell.Style {
+	if s, ok := config.Colorscheme["debug-value"]; ok {
+		fg, _, _ := s.Decompose()
+		return style.Foreground(fg)
+	}
+	return style.Dim(true)
+}
+
 func (w *BufWindow) displayStatusLine() {
 	_, h := screen.Screen.Size()
 	infoY := h
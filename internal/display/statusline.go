@@ -49,6 +49,11 @@ var statusInfo = map[string]func(*buffer.Buffer) string{
 	},
 }
 
+// SetStatusInfoFn registers fn as the statusline function $(name)
+func SetStatusInfoFn(name string, fn func(*buffer.Buffer) string) {
+	statusInfo[name] = fn
+}
+
 func SetStatusInfoFnLua(fn string) {
 	luaFn := strings.Split(fn, ".")
 	if len(luaFn) <= 1 {
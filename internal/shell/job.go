@@ -29,6 +29,13 @@ type JobFunction struct {
 	Args     []interface{}
 }
 
+// Post runs f on the main loop the same way the callbacks of jobs are run
+func Post(f func()) {
+	Jobs <- JobFunction{
+		Function: func(string, []interface{}) { f() },
+	}
+}
+
 // A CallbackFile is the data structure that makes it possible to catch stderr and stdout write events
 type CallbackFile struct {
 	io.Writer
@@ -0,0 +1,45 @@
+package xdebug
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// SetVariable sets the variable in the current session with property_set
+// and calls done with the result. Variables, watches and inline values are
+// fetched again after the value was set.
+func (xc *Client) SetVariable(a debugger.Assignment, done func(error)) error {
+	s := xc.current
+	if s == nil || s.status != "break" {
+		return fmt.Errorf("set: the debugger is not stopped at a break")
+	}
+
+	args := fmt.Sprintf("-n %s -c %d -d %d", quoteArg(a.Name), a.Context, a.Depth)
+	if a.Type != "" {
+		args += " -t " + a.Type
+	}
+	args += " -l " + strconv.Itoa(len(a.Value))
+
+	_, err := s.conn.send("property_set", args, []byte(a.Value), func(resp Response) {
+		switch {
+		case resp.Error.Code != 0:
+			done(fmt.Errorf("set %s: error %d: %s", a.Name, resp.Error.Code, resp.Error.Message.Text))
+			return
+		case resp.Success != 1:
+			done(fmt.Errorf("set %s: the engine did not set the value", a.Name))
+			return
+		}
+		done(nil)
+
+		s.refreshVariables()
+		s.evalWatches()
+		s.evalInline()
+	})
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
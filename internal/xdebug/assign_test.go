@@ -0,0 +1,34 @@
+package xdebug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+func TestSetVariable(t *testing.T) {
+	xc, _, engine := testSession(t)
+	defer engine.Close()
+	cmds := engineCommands(engine)
+
+	var setErr error
+	done := func(err error) { setErr = err }
+
+	a := debugger.Assignment{Name: "$s", Type: "string", Value: "héllo"}
+	assert.NoError(t, xc.SetVariable(a, done))
+	assert.Equal(t, `property_set -i 1 -n "$s" -c 0 -d 0 -t string -l 6 -- aMOpbGxv`, nextCommand(t, cmds))
+
+	// the variables are fetched again after the value was set
+	writePacket(t, engine, `<response command="property_set" transaction_id="1" success="1"/>`)
+	runJob(t)
+	assert.NoError(t, setErr)
+	assert.Equal(t, "context_names -i 2 -d 0", nextCommand(t, cmds))
+
+	a = debugger.Assignment{Name: "$a", Depth: 1, Value: "[1]"}
+	assert.NoError(t, xc.SetVariable(a, done))
+	assert.Equal(t, `property_set -i 3 -n "$a" -c 0 -d 1 -l 3 -- WzFd`, nextCommand(t, cmds))
+	writePacket(t, engine, `<response command="property_set" transaction_id="3" success="0"/>`)
+	runJob(t)
+	assert.EqualError(t, setErr, "set $a: the engine did not set the value")
+}
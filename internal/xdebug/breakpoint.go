@@ -0,0 +1,165 @@
+package xdebug
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// breakpointArgs returns breakpoint_set arguments and data for the breakpoint.
+func (xc *Client) breakpointArgs(bp *debugger.Breakpoint) (string, []byte, error) {
+	args := "-t " + bp.Type
+	var data []byte
+
+	switch bp.Type {
+	case "line", "conditional":
+		uri, err := xc.remoteURI(bp.File)
+		if err != nil {
+			return "", nil, err
+		}
+		args += fmt.Sprintf(" -f %s -n %d", uri, bp.Line)
+		if bp.Expression != "" {
+			data = []byte(bp.Expression)
+		}
+	case "call", "return":
+		args += " -m " + bp.Function
+	case "exception":
+		args += " -x " + bp.Exception
+	}
+
+	if bp.HitCondition != "" {
+		args += fmt.Sprintf(" -h %d -o %s", bp.HitValue, bp.HitCondition)
+	}
+
+	return args, data, nil
+}
+
+// setBreakpoint sends breakpoint_set for the breakpoint and remembers
+// the engine breakpoint ID.
+func (s *session) setBreakpoint(bp *debugger.Breakpoint) error {
+	args, data, err := s.xc.breakpointArgs(bp)
+	if err != nil {
+		return err
+	}
+	if s.breakpointIDs == nil {
+		s.breakpointIDs = make(map[*debugger.Breakpoint]int)
+	}
+	// the ID is 0 while breakpoint_set is in flight
+	s.breakpointIDs[bp] = 0
+	return s.command("breakpoint_set", args, data, func(resp Response) {
+		if _, ok := s.breakpointIDs[bp]; !ok {
+			// removed while breakpoint_set was in flight
+			if err := s.command("breakpoint_remove", fmt.Sprintf("-d %d", resp.ID), nil, nil); err != nil {
+				log.Println(err)
+				s.xc.Editor.Error(err)
+			}
+			return
+		}
+		s.breakpointIDs[bp] = resp.ID
+	})
+}
+
+// AddBreakpoint adds the breakpoint and sets it in the engines of all
+// sessions.
+func (xc *Client) AddBreakpoint(bp debugger.Breakpoint) error {
+	xc.extraBreakpoints = append(xc.extraBreakpoints, &bp)
+	for _, s := range xc.sessions {
+		if err := s.setBreakpoint(&bp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveBreakpoint removes the n-th breakpoint listed by ListBreakpoints.
+func (xc *Client) RemoveBreakpoint(n int) error {
+	if n < 1 || n > len(xc.extraBreakpoints) {
+		return fmt.Errorf("no breakpoint %d", n)
+	}
+
+	bp := xc.extraBreakpoints[n-1]
+	xc.extraBreakpoints = append(xc.extraBreakpoints[:n-1], xc.extraBreakpoints[n:]...)
+
+	for _, s := range xc.sessions {
+		id, ok := s.breakpointIDs[bp]
+		if !ok {
+			continue
+		}
+		delete(s.breakpointIDs, bp)
+		if id == 0 {
+			// the handler of breakpoint_set removes it
+			continue
+		}
+		if err := s.command("breakpoint_remove", fmt.Sprintf("-d %d", id), nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveBreakpoint records the line the engine resolved the breakpoint to.
+// Line breakpoints on lines without code are moved to the next line with code.
+func (s *session) resolveBreakpoint(b breakpoint) {
+	if b.Line == 0 {
+		return
+	}
+	if s.resolvedLines == nil {
+		s.resolvedLines = make(map[int]int)
+	}
+	s.resolvedLines[b.ID] = b.Line
+
+	for fname, engine := range s.engineBreakpoints {
+		for line, id := range engine {
+			if id == b.ID && line != b.Line {
+				s.xc.Editor.Message(fmt.Sprintf("breakpoint %s:%d resolved to line %d", filepath.Base(fname), line, b.Line))
+			}
+		}
+	}
+}
+
+// ListBreakpoints writes the numbered list of the command line breakpoints.
+// Lines the current engine resolved them to are added.
+func (xc *Client) ListBreakpoints(w io.Writer) {
+	for i, bp := range xc.extraBreakpoints {
+		fmt.Fprintf(w, "%2d %s", i+1, bp.String())
+		if s := xc.current; s != nil {
+			if id, ok := s.breakpointIDs[bp]; ok {
+				if line, ok := s.resolvedLines[id]; ok && line != bp.Line {
+					fmt.Fprintf(w, " (line %d)", line)
+				}
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func dumpBreakpoints(w io.Writer, br []breakpoint) {
+	fmt.Fprintln(w, "=== breakpoints ===")
+	for _, b := range br {
+		fmt.Fprintf(w, "%d %s %s", b.ID, b.Type, b.State)
+		switch b.Type {
+		case "line", "conditional":
+			fmt.Fprintf(w, " %s:%d", b.Filename, b.Line)
+		case "call", "return":
+			fmt.Fprintf(w, " %s", b.Function)
+		case "exception":
+			fmt.Fprintf(w, " %s", b.Exception)
+		}
+		if b.Expression.Text != "" {
+			expr := b.Expression.Text
+			if d, err := base64.StdEncoding.DecodeString(expr); err == nil && b.Expression.Encoding == "base64" {
+				expr = string(d)
+			}
+			fmt.Fprintf(w, " if %s", expr)
+		}
+		if b.HitCondition != "" {
+			fmt.Fprintf(w, " hit%s%d", b.HitCondition, b.HitValue)
+		}
+		fmt.Fprintf(w, " hits: %d\n", b.HitCount)
+	}
+	fmt.Fprintln(w, "===================")
+}
@@ -0,0 +1,89 @@
+package xdebug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+func TestBreakpointArgs(t *testing.T) {
+	xc := &Client{Root: "/p"}
+
+	args, data, err := xc.breakpointArgs(&debugger.Breakpoint{Type: "conditional", File: "src/a.php", Line: 12, Expression: "$x > 5", HitCondition: ">=", HitValue: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, "-t conditional -f file:///p/src/a.php -n 12 -h 3 -o >=", args)
+	assert.Equal(t, "$x > 5", string(data))
+
+	args, data, err = xc.breakpointArgs(&debugger.Breakpoint{Type: "line", File: "/p/b.php", Line: 7})
+	assert.NoError(t, err)
+	assert.Equal(t, "-t line -f file:///p/b.php -n 7", args)
+	assert.Nil(t, data)
+
+	args, _, err = xc.breakpointArgs(&debugger.Breakpoint{Type: "exception", Exception: "*", HitCondition: "%", HitValue: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "-t exception -x * -h 2 -o %", args)
+
+	args, _, err = xc.breakpointArgs(&debugger.Breakpoint{Type: "return", Function: "strlen"})
+	assert.NoError(t, err)
+	assert.Equal(t, "-t return -m strlen", args)
+}
+
+func TestBreakpointResolved(t *testing.T) {
+	xc, s, engine := testSession(t)
+	defer engine.Close()
+	cmds := engineCommands(engine)
+	s.conn.onNotify = s.onNotify
+
+	xc.SetBreakpoints("/srv/app/index.php", []int{4})
+	assert.Equal(t, "breakpoint_set -i 1 -t line -f file:///srv/app/index.php -n 4", nextCommand(t, cmds))
+	writePacket(t, engine, `<response command="breakpoint_set" transaction_id="1" id="7"/>`)
+	runJob(t)
+	assert.NoError(t, xc.AddBreakpoint(debugger.Breakpoint{Type: "line", File: "/srv/app/a.php", Line: 2}))
+	assert.Equal(t, "breakpoint_set -i 2 -t line -f file:///srv/app/a.php -n 2", nextCommand(t, cmds))
+	writePacket(t, engine, `<response command="breakpoint_set" transaction_id="2" id="8"/>`)
+	runJob(t)
+
+	// the engine moves the breakpoints to lines with code
+	writePacket(t, engine, `<notify name="breakpoint_resolved"><breakpoint type="line" resolved="resolved" filename="file:///srv/app/index.php" lineno="5" id="7"/></notify>`)
+	writePacket(t, engine, `<notify name="breakpoint_resolved"><breakpoint type="line" resolved="resolved" filename="file:///srv/app/a.php" lineno="3" id="8"/></notify>`)
+	runJob(t)
+	runJob(t)
+	assert.Equal(t, "breakpoint index.php:4 resolved to line 5", xc.Editor.(*testEditor).messages[0])
+	var b strings.Builder
+	xc.ListBreakpoints(&b)
+	assert.Equal(t, " 1 line /srv/app/a.php:2 (line 3)\n", b.String())
+}
+
+func TestBreakpointCommands(t *testing.T) {
+	xc, s, engine := testSession(t)
+	defer engine.Close()
+	cmds := engineCommands(engine)
+	s.currFile = "file:///srv/app/index.php"
+	s.currLine = 3
+
+	// b adds a breakpoint at the break, the arguments are the ones of bp
+	assert.NoError(t, xc.ProcessCommand([]string{"b"}))
+	assert.Equal(t, "breakpoint_set -i 1 -t line -f file:///srv/app/index.php -n 3", nextCommand(t, cmds))
+	assert.NoError(t, xc.ProcessCommand([]string{"b", "cond", "if", "$i", ">", "2"}))
+	assert.Equal(t, "breakpoint_set -i 2 -t conditional -f file:///srv/app/index.php -n 3 -- JGkgPiAy", nextCommand(t, cmds))
+
+	// removed while breakpoint_set is in flight
+	assert.NoError(t, xc.RemoveBreakpoint(1))
+	writePacket(t, engine, `<response command="breakpoint_set" transaction_id="1" id="5"/>`)
+	runJob(t)
+	assert.Equal(t, "breakpoint_remove -i 3 -d 5", nextCommand(t, cmds))
+	writePacket(t, engine, `<response command="breakpoint_set" transaction_id="2" id="6"/>`)
+	runJob(t)
+	assert.Equal(t, map[*debugger.Breakpoint]int{xc.extraBreakpoints[0]: 6}, s.breakpointIDs)
+
+	// the engine breakpoints are listed in the output
+	assert.NoError(t, xc.ProcessCommand([]string{"bl"}))
+	assert.Equal(t, "breakpoint_list -i 4", nextCommand(t, cmds))
+	writePacket(t, engine, `<response command="breakpoint_list" transaction_id="4"><breakpoint type="conditional" filename="file:///srv/app/index.php" lineno="3" state="enabled" hit_count="1" id="6"><expression encoding="base64"><![CDATA[JGkgPiAy]]></expression></breakpoint></response>`)
+	runJob(t)
+	ed := xc.Editor.(*testEditor)
+	assert.Contains(t, ed.output[1], "6 conditional enabled file:///srv/app/index.php:3 if $i > 2 hits: 1\n")
+	assert.Equal(t, "1 breakpoints in the engine, listed in the output of session 1", ed.messages[len(ed.messages)-1])
+}
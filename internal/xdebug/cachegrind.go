@@ -0,0 +1,450 @@
+package xdebug
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profile is a profile of a script written by the Xdebug profiler in the
+// callgrind format, e.g. cachegrind.out.1234.
+type Profile struct {
+	Cmd       string
+	Events    []string             // event names, e.g. Time_(10ns) and Memory_(bytes)
+	Totals    []int64              // total cost of each event
+	Functions []*FunctionCost      // functions sorted by inclusive cost of the first event
+	Lines     map[string]LineCosts // file -> costs of its lines
+}
+
+// LineCosts maps 1-based lines to their costs. The cost of a line is the
+// cost of its own code plus the inclusive cost of the calls on the line.
+type LineCosts map[int][]int64
+
+// FunctionCost is the cost of a function summed over all its calls.
+type FunctionCost struct {
+	Name      string
+	File      string
+	Line      int     // first line with self cost, usually the declaration
+	Calls     int     // number of calls
+	Self      []int64 // exclusive cost of each event
+	Inclusive []int64 // cost of each event including called functions
+}
+
+// Event returns the index of the first event with the prefix, e.g. Time or
+// Memory, or -1 if the profile has no such event.
+func (p *Profile) Event(prefix string) int {
+	for i, e := range p.Events {
+		if strings.HasPrefix(e, prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+var eventUnitRegexp = regexp.MustCompile(`\((\d*)(ns|us|µs|ms|s)\)$`)
+
+// TimeUnit returns the duration of one unit of the time event. Xdebug 3
+// names the unit like Time_(10ns), Xdebug 2 writes Time in 100ns units.
+func (p *Profile) TimeUnit() time.Duration {
+	i := p.Event("Time")
+	if i < 0 {
+		return 0
+	}
+	m := eventUnitRegexp.FindStringSubmatch(p.Events[i])
+	if m == nil {
+		return 100 * time.Nanosecond
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		n = 1
+	}
+	unit := map[string]time.Duration{
+		"ns": time.Nanosecond,
+		"us": time.Microsecond,
+		"µs": time.Microsecond,
+		"ms": time.Millisecond,
+		"s":  time.Second,
+	}[m[2]]
+	return time.Duration(n) * unit
+}
+
+// ReadProfile reads the profile file, which may be gzip compressed.
+func ReadProfile(fname string) (*Profile, error) {
+	var p *Profile
+	err := readFile(fname, func(r io.Reader) error {
+		var err error
+		p, err = ParseProfile(r)
+		return err
+	})
+	return p, err
+}
+
+// readFile calls parse with the contents of the file, which is
+// uncompressed if the file name ends with .gz.
+func readFile(fname string, parse func(r io.Reader) error) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		return fmt.Errorf("open. error: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(fname, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: read. error: %w", fname, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	if err := parse(r); err != nil {
+		return fmt.Errorf("%s: %w", fname, err)
+	}
+	return nil
+}
+
+// FindProfile returns the newest cachegrind.out.* file of the directories
+// or "" if there is none.
+func FindProfile(dirs ...string) string {
+	return findNewest("cachegrind.out.*", dirs)
+}
+
+// findNewest returns the newest file matching the pattern in the
+// directories or "" if there is none.
+func findNewest(pattern string, dirs []string) string {
+	var newest string
+	var mtime time.Time
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			continue
+		}
+		for _, fname := range files {
+			fi, err := os.Stat(fname)
+			if err == nil && fi.ModTime().After(mtime) {
+				newest, mtime = fname, fi.ModTime()
+			}
+		}
+	}
+	return newest
+}
+
+// LoadProfile reads the profile file and maps its engine files to local
+// files with the path mappings of the project.
+func (xc *Client) LoadProfile(fname string) (*Profile, error) {
+	if err := xc.readConfig(); err != nil {
+		return nil, err
+	}
+	p, err := ReadProfile(fname)
+	if err != nil {
+		return nil, err
+	}
+	xc.mapProfile(p)
+	return p, nil
+}
+
+func (xc *Client) mapProfile(p *Profile) {
+	for _, f := range p.Functions {
+		f.File = xc.localName(f.File)
+	}
+	lines := make(map[string]LineCosts)
+	for file, costs := range p.Lines {
+		lines[xc.localName(file)] = costs
+	}
+	p.Lines = lines
+}
+
+// localName returns the local file of the engine path written by the
+// profiler or the tracer, or the path itself if it is not mapped.
+func (xc *Client) localName(file string) string {
+	// internal functions are in php:internal
+	if !strings.HasPrefix(file, "/") {
+		return file
+	}
+	if fname, err := xc.LocalPath(pathToURI(file)); err == nil {
+		return fname
+	}
+	return file
+}
+
+// profileParser holds the state of ParseProfile. Files and functions may
+// be compressed to "(id) name" on first use and "(id)" later on.
+type profileParser struct {
+	p         *Profile
+	files     map[string]string
+	fns       map[string]string
+	functions map[string]*FunctionCost
+
+	fl, fi, fn string // current file, file of the costs and function
+	fnCost     *FunctionCost
+	blocks     map[*FunctionCost]int // fn blocks of the function
+
+	cfl, cfn string // target of the next call
+	call     bool   // the next cost line is the cost of calls
+	calls    int    // number of the calls
+	line     int    // last line for relative positions
+
+	callCosts map[*FunctionCost][]int64 // inclusive costs of calls to the function
+}
+
+// ParseProfile parses a profile in the callgrind format.
+func ParseProfile(r io.Reader) (*Profile, error) {
+	pp := &profileParser{
+		p:         &Profile{Lines: make(map[string]LineCosts)},
+		files:     make(map[string]string),
+		fns:       make(map[string]string),
+		functions: make(map[string]*FunctionCost),
+		blocks:    make(map[*FunctionCost]int),
+		callCosts: make(map[*FunctionCost][]int64),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		if err := pp.parseLine(scanner.Text()); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("profile read. error: %w", err)
+	}
+	if len(pp.p.Events) == 0 {
+		return nil, fmt.Errorf("not a cachegrind profile")
+	}
+
+	p := pp.p
+	for _, f := range p.Functions {
+		// functions without callers like {main} are called once per block
+		if f.Calls == 0 {
+			f.Calls = pp.blocks[f]
+		}
+		// functions only known from calls have no costs of their own
+		if pp.blocks[f] == 0 {
+			copy(f.Inclusive, pp.callCosts[f])
+		}
+	}
+	if len(p.Totals) == 0 {
+		p.Totals = make([]int64, len(p.Events))
+		for _, f := range p.Functions {
+			addCosts(p.Totals, f.Self)
+		}
+	}
+	p.Sort(func(a, b *FunctionCost) bool { return a.Inclusive[0] > b.Inclusive[0] })
+	return p, nil
+}
+
+// Sort sorts the functions of the profile, keeping the order of equal ones.
+func (p *Profile) Sort(less func(a, b *FunctionCost) bool) {
+	sort.SliceStable(p.Functions, func(i, j int) bool {
+		return less(p.Functions[i], p.Functions[j])
+	})
+}
+
+func (pp *profileParser) parseLine(l string) error {
+	p := pp.p
+	l = strings.TrimSpace(l)
+	if l == "" || l[0] == '#' {
+		return nil
+	}
+
+	if i := strings.Index(l, ": "); i > 0 && !strings.Contains(l[:i], "=") {
+		key, value := l[:i], strings.TrimSpace(l[i+2:])
+		switch key {
+		case "cmd":
+			p.Cmd = value
+		case "events":
+			p.Events = strings.Fields(value)
+		case "positions":
+			if value != "line" {
+				return fmt.Errorf("unsupported positions %q", value)
+			}
+		case "summary", "totals":
+			costs, err := pp.parseCosts(strings.Fields(value))
+			if err != nil {
+				return err
+			}
+			p.Totals = costs
+		}
+		return nil
+	}
+
+	if i := strings.IndexByte(l, '='); i > 0 {
+		key, value := l[:i], l[i+1:]
+		switch key {
+		case "fl":
+			pp.fl = pp.name(pp.files, value)
+			pp.fi = pp.fl
+		case "fi", "fe":
+			pp.fi = pp.name(pp.files, value)
+		case "fn":
+			pp.fn = pp.name(pp.fns, value)
+			pp.fi = pp.fl
+			pp.fnCost = pp.function(pp.fl, pp.fn)
+			pp.blocks[pp.fnCost]++
+		case "cfl", "cfi":
+			pp.cfl = pp.name(pp.files, value)
+		case "cfn":
+			pp.cfn = pp.name(pp.fns, value)
+		case "calls":
+			fields := strings.Fields(value)
+			if len(fields) == 0 {
+				return fmt.Errorf("calls without count")
+			}
+			calls, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return fmt.Errorf("calls count. error: %w", err)
+			}
+			pp.call, pp.calls = true, calls
+		}
+		return nil
+	}
+
+	if len(p.Events) == 0 {
+		return fmt.Errorf("cost line before events")
+	}
+	return pp.costLine(strings.Fields(l))
+}
+
+// costLine adds the cost line to the current function and file. Cost lines
+// after calls= are the inclusive cost of the call.
+func (pp *profileParser) costLine(fields []string) error {
+	if pp.fnCost == nil {
+		return fmt.Errorf("cost line outside of a function")
+	}
+	line, err := pp.position(fields[0])
+	if err != nil {
+		return err
+	}
+	costs, err := pp.parseCosts(fields[1:])
+	if err != nil {
+		return err
+	}
+
+	f := pp.fnCost
+	if pp.call {
+		cfl := pp.cfl
+		if cfl == "" {
+			cfl = pp.fl
+		}
+		callee := pp.function(cfl, pp.cfn)
+		callee.Calls += pp.calls
+		if pp.callCosts[callee] == nil {
+			pp.callCosts[callee] = make([]int64, len(pp.p.Events))
+		}
+		addCosts(pp.callCosts[callee], costs)
+		addCosts(f.Inclusive, costs)
+		pp.cfl, pp.cfn, pp.call = "", "", false
+	} else {
+		if f.Line == 0 {
+			f.Line = line
+		}
+		addCosts(f.Self, costs)
+		addCosts(f.Inclusive, costs)
+	}
+
+	lines := pp.p.Lines[pp.fi]
+	if lines == nil {
+		lines = make(LineCosts)
+		pp.p.Lines[pp.fi] = lines
+	}
+	if lines[line] == nil {
+		lines[line] = make([]int64, len(pp.p.Events))
+	}
+	addCosts(lines[line], costs)
+	return nil
+}
+
+// name returns the name of the compressed name "(id) name" or "(id)" and
+// stores new ones in names.
+func (pp *profileParser) name(names map[string]string, s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") {
+		return s
+	}
+	i := strings.IndexByte(s, ')')
+	if i < 0 {
+		return s
+	}
+	id, name := s[:i+1], strings.TrimSpace(s[i+1:])
+	if name == "" {
+		return names[id]
+	}
+	names[id] = name
+	return name
+}
+
+// function returns the costs of the function, adding it if it is new.
+func (pp *profileParser) function(file, name string) *FunctionCost {
+	key := file + "\x00" + name
+	f, ok := pp.functions[key]
+	if !ok {
+		n := len(pp.p.Events)
+		f = &FunctionCost{
+			Name:      name,
+			File:      file,
+			Self:      make([]int64, n),
+			Inclusive: make([]int64, n),
+		}
+		pp.functions[key] = f
+		pp.p.Functions = append(pp.p.Functions, f)
+	}
+	return f
+}
+
+// position returns the line of the position, which may be relative to the
+// last one like +2, -1 or * for the same line.
+func (pp *profileParser) position(s string) (int, error) {
+	line := pp.line
+	switch {
+	case s == "*":
+	case s[0] == '+' || s[0] == '-':
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("position. error: %w", err)
+		}
+		line += n
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("position. error: %w", err)
+		}
+		line = n
+	}
+	pp.line = line
+	return line, nil
+}
+
+// parseCosts parses the costs of the events, missing ones are 0.
+func (pp *profileParser) parseCosts(fields []string) ([]int64, error) {
+	costs := make([]int64, len(pp.p.Events))
+	for i, f := range fields {
+		if i >= len(costs) {
+			break
+		}
+		n, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cost. error: %w", err)
+		}
+		costs[i] = n
+	}
+	return costs, nil
+}
+
+func addCosts(sum, costs []int64) {
+	for i := range sum {
+		if i < len(costs) {
+			sum[i] += costs[i]
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package xdebug
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testProfile = `version: 1
+creator: xdebug 3.1.0 (PHP 8.0.0)
+cmd: /var/www/index.php
+part: 1
+positions: line
+
+events: Time_(10ns) Memory_(bytes)
+
+fl=(1) php:internal
+fn=(1) php::strlen
+1 5 0
+
+fl=(2) /var/www/lib.php
+fn=(2) foo
+3 100 1000
+cfl=(1)
+cfn=(1)
+calls=1 0 0
+4 5 0
+
+fl=(2)
+fn=(2)
+3 200 2000
+cfl=(1)
+cfn=(1)
+calls=1 0 0
++1 5 0
+
+fl=(3) /var/www/index.php
+fn=(3) {main}
+1 50 300
+cfl=(2)
+cfn=(2)
+calls=2 3 0
+5 310 3000
+
+summary: 365 3300
+`
+
+func TestParseProfile(t *testing.T) {
+	p, err := ParseProfile(strings.NewReader(testProfile))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "/var/www/index.php", p.Cmd)
+	assert.Equal(t, []int64{365, 3300}, p.Totals)
+	assert.Equal(t, 10*time.Nanosecond, p.TimeUnit())
+	assert.Equal(t, 1, p.Event("Memory"))
+
+	assert.Equal(t, []*FunctionCost{
+		{Name: "{main}", File: "/var/www/index.php", Line: 1, Calls: 1, Self: []int64{50, 300}, Inclusive: []int64{360, 3300}},
+		{Name: "foo", File: "/var/www/lib.php", Line: 3, Calls: 2, Self: []int64{300, 3000}, Inclusive: []int64{310, 3000}},
+		{Name: "php::strlen", File: "php:internal", Line: 1, Calls: 2, Self: []int64{5, 0}, Inclusive: []int64{5, 0}},
+	}, p.Functions)
+
+	assert.Equal(t, LineCosts{3: {300, 3000}, 4: {10, 0}}, p.Lines["/var/www/lib.php"])
+	assert.Equal(t, LineCosts{1: {50, 300}, 5: {310, 3000}}, p.Lines["/var/www/index.php"])
+}
+
+func TestParseProfileErrors(t *testing.T) {
+	_, err := ParseProfile(strings.NewReader("hello\n"))
+	assert.Error(t, err)
+
+	_, err = ParseProfile(strings.NewReader("events: Time\n1 2\n"))
+	assert.EqualError(t, err, "line 2: cost line outside of a function")
+
+	p, err := ParseProfile(strings.NewReader("events: Time\nfl=a.php\nfn=f\n2 7\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 100*time.Nanosecond, p.TimeUnit())
+	assert.Equal(t, []int64{7}, p.Totals)
+}
+
+func TestLoadProfileMapsFiles(t *testing.T) {
+	xc := &Client{
+		Root:   "/home/me/project",
+		Config: Config{PathMappings: []PathMapping{{Remote: "/var/www", Local: "."}}},
+	}
+	p, err := ParseProfile(strings.NewReader(testProfile))
+	assert.NoError(t, err)
+	xc.mapProfile(p)
+	assert.Equal(t, "/home/me/project/index.php", p.Functions[0].File)
+	assert.Equal(t, "php:internal", p.Functions[2].File)
+	assert.Contains(t, p.Lines, "/home/me/project/lib.php")
+}
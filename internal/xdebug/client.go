@@ -7,112 +7,181 @@ import (
 	"io"
 	"log"
 	"net"
-	"os"
-	"os/exec"
+	"path"
 	"strconv"
 	"strings"
+	"time"
 
-	"gopkg.in/yaml.v2"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+	"github.com/zyedidia/micro/v2/internal/shell"
 )
 
-// Editor is a callback interface for editor automation.
-type Editor interface {
-	OpenCmd([]string)           // open FILE
-	GotoCmd([]string)           // goto LINE
-	Message(msg ...interface{}) // shows message on status bar
-	Error(msg ...interface{})   // shows error message on status bar
+// Config is the configuration of the client. Options holds the editor
+// options, the project debug file .micro/debug.yaml overrides them.
+type Config struct {
+	BasePath     string        `yaml:"base_path"` // source root dir i.e. file:///path/to/the/project/root/
+	InitCommand  string        `yaml:"init"`      // command which calls php project after xdebug started
+	Breakpoints  []string      `yaml:"breakpoints"`
+	PathMappings []PathMapping `yaml:"path_mappings"` // remote to local directories, first match wins
+
+	Address string `yaml:"address"` // listen address, unix:/path for a Unix domain socket
+	Port    int    `yaml:"port"`    // listen port, DefaultPort if 0
+	Timeout int    `yaml:"timeout"` // seconds to wait for the engine, 0 waits until stopped
+	IDEKey  string `yaml:"idekey"`  // if set, connections with other idekeys are rejected
+
+	BreakFirst bool `yaml:"break_first"` // break on the first line of each session
+
+	Proxy string `yaml:"proxy"` // DBGp proxy address for proxyinit, host:port
+
+	StepFilters []string `yaml:"step_filters"` // globs of files skipped by step_into, e.g. vendor/**
+
+	MaxChildren int `yaml:"max_children"` // children of a property fetched at once, engine default if 0
+	MaxData     int `yaml:"max_data"`     // bytes of a value fetched at once, engine default if 0
+	MaxDepth    int `yaml:"max_depth"`    // levels of nested properties fetched at once, engine default if 0
+
+	Record string `yaml:"record"` // transcript file the packets of sessions are appended to, if set
+
+	Launches []Launch `yaml:"configurations"` // named launch configurations for start NAME
 }
 
-// Client is a xdebug client. Inits yaml-tagged fields from ./init.yaml file.
+// Client is a xdebug client. The configuration is read from the project
+// debug file on start.
 type Client struct {
-	BasePath    string   `yaml:"base_path"` // source root dir i.e. file:///path/to/the/project/root/
-	InitCommand string   `yaml:"init"`      // command which calls php project after xdebug started
-	Breakpoints []string `yaml:breakpoints`
-
-	Editor Editor // callback interface for editor automation
-
-	listener *net.TCPListener // listener on :9004
-	conn     *net.TCPConn     // accepted xdebugger connection
-	transID  int              // transaction ID
-	currLine int
-	currFile string
-	prevLine string
-	started  bool
-}
+	Config
+
+	Options Config // editor options, the defaults of the project debug file
+
+	Root string // project root, relative local paths of PathMappings are relative to it
+
+	Editor debugger.Editor // callback interface for editor automation
+
+	SessionIDs *debugger.SessionCounter // numbers the sessions, a counter of the client if nil
+
+	listener  net.Listener // listener for engine connections
+	listening bool         // listener is kept open between sessions
+	started   bool
+	proxy     string  // DBGp proxy the IDE key is registered with
+	proxyKey  string  // IDE key registered with the proxy
+	launch    *Launch // launch configuration of the last start
+
+	recorder *recorder // transcript of the sessions, nil if not recording
 
-func (xc *Client) jumpToFile() {
-	fname := strings.TrimPrefix(xc.currFile, xc.BasePath)
-	log.Println("open", fname, xc.currLine)
-	xc.Editor.OpenCmd([]string{fname})
-	xc.Editor.GotoCmd([]string{strconv.Itoa(xc.currLine)})
+	sessions []*session // connected engines
+	current  *session   // session receiving step and eval commands
+
+	breakpoints      map[string][]int       // local file -> 1-based lines of line breakpoints
+	extraBreakpoints []*debugger.Breakpoint // breakpoints added from the command line
+
+	watches []*debugger.Watch
 }
 
-func (xc *Client) handleResponse(resp Response) error {
-	switch resp.Command {
-	case "step_over", "step_into", "step_out":
-		xc.currFile = resp.Message.Filename
-		xc.currLine = resp.Message.Line
-		xc.jumpToFile()
-	case "stack_get":
-		var b bytes.Buffer
-		xc.dumpStack(&b, resp)
-		log.Println("\n", b.String())
-	case "run":
-		if resp.Status == "stopping" && resp.Reason == "ok" {
-			if err := xc.Close(); err != nil {
-				log.Println(err)
-			}
-			xc.started = false
-			xc.Editor.Message("debugger stopped. F8 to start")
-			return nil
+var _ debugger.Backend = (*Client)(nil)
+
+// jumpTo opens the file of the engine file URI at the line. Code without a
+// local file, e.g. eval()'d code with dbgp: URIs, is fetched with source and
+// opened read-only.
+func (s *session) jumpTo(uri string, line int) {
+	xc := s.xc
+	if fname, err := xc.LocalFile(uri); err == nil {
+		log.Println("open", fname, line)
+		xc.Editor.OpenCmd([]string{fname})
+		xc.Editor.GotoCmd([]string{strconv.Itoa(line)})
+		return
+	}
+
+	log.Println("open source", uri, line)
+	_, err := s.conn.send("source", "-f "+uri, nil, func(resp Response) {
+		if resp.Error.Code != 0 {
+			xc.Editor.Error(fmt.Sprintf("source %s: error %d: %s", uri, resp.Error.Code, resp.Error.Message.Text))
+			return
 		}
-		if resp.Status == "break" && resp.Reason == "ok" && resp.Message.Filename != "" {
-			xc.currFile = resp.Message.Filename
-			xc.currLine = resp.Message.Line
-			xc.jumpToFile()
-			xc.transID++
+		b, err := base64.StdEncoding.DecodeString(resp.Text)
+		if err != nil {
+			xc.Editor.Error(fmt.Sprintf("source %s: %v", uri, err))
+			return
+		}
+		xc.Editor.OpenSource(uri, string(b), line)
+	})
+	if err != nil {
+		log.Println(err)
+		xc.Editor.Error(err)
+	}
+}
 
-			s := fmt.Sprintf("stack_get -i %d\x00", xc.transID)
-			xc.transID++
-			resp, err := xc.send(s)
-			if err != nil {
-				return err
-			}
+func (s *session) handleResponse(resp Response) error {
+	xc := s.xc
 
-			if resp.Command != "stack_get" {
-				log.Fatal("returned command is not stack_get")
-			}
+	if resp.Error.Code != 0 {
+		return fmt.Errorf("%s: error %d: %s", resp.Command, resp.Error.Code, resp.Error.Message.Text)
+	}
 
-			if err := xc.handleResponse(resp); err != nil {
-				return err
-			}
+	if resp.Status != "" && resp.Status != s.status {
+		s.status = resp.Status
+		xc.Editor.SessionsChanged()
+		if s.isCurrent() {
+			xc.Editor.LocationChanged()
+		}
+	}
 
-			s = fmt.Sprintf("source -i %d -f %s\x00", xc.transID, xc.currFile)
-			xc.transID++
-			resp, err = xc.send(s)
-			if err != nil {
-				return err
+	switch resp.Command {
+	case "step_over", "step_into", "step_out", "run", "break":
+		if resp.Status == "stopping" || resp.Status == "stopped" {
+			xc.endSession(s)
+			return nil
+		}
+		if resp.Status == "break" {
+			s.removeRunTo()
+		}
+		if resp.Status == "break" && resp.Message.Filename != "" {
+			if s.skipFiltered(resp.Message.Filename, resp.Message.Line) {
+				return nil
 			}
-
-			if err := xc.handleResponse(resp); err != nil {
-				return err
+			s.trackExecuted(resp.Message.Filename)
+			s.currFile = resp.Message.Filename
+			s.currLine = resp.Message.Line
+			if !s.isCurrent() {
+				xc.Editor.Message(fmt.Sprintf("session %d: break at %s:%d", s.id, path.Base(s.currFile), s.currLine))
+				return nil
 			}
+			s.jumpTo(s.currFile, s.currLine)
+			xc.Editor.LocationChanged()
+			s.refresh()
+		}
+		if (resp.Command == "run" || resp.Command == "break") && s.isCurrent() {
+			xc.Editor.Message(fmt.Sprintln(resp.Command+":", resp.Status, resp.Reason))
 		}
-		xc.Editor.Message(fmt.Sprintln("run:", resp.Status, resp.Reason))
+	case "stack_get":
+		var b bytes.Buffer
+		xc.dumpStack(&b, resp)
+		log.Println("\n", b.String())
+		s.stack = nil
+		if len(resp.Stack) > 0 {
+			s.enterFunction(resp.Stack[0].Where)
+		}
+		for _, f := range resp.Stack {
+			s.stack = append(s.stack, debugger.Frame{
+				Level: f.Level,
+				File:  f.Filename,
+				Line:  f.Line,
+				Where: f.Where,
+			})
+		}
+		xc.Editor.StackChanged()
 	case "breakpoint_list":
-		//		dumpBreakpoints(resp.Breakpoints)
+		var b bytes.Buffer
+		dumpBreakpoints(&b, resp.Breakpoints)
+		xc.Editor.Output(s.id, b.String())
+		xc.Editor.Message(fmt.Sprintf("%d breakpoints in the engine, listed in the output of session %d", len(resp.Breakpoints), s.id))
+	case "detach":
+		xc.endSession(s)
 	case "source":
 		b, err := base64.StdEncoding.DecodeString(resp.Text)
 		if err != nil {
 			return err
 		}
 
-		lines := strings.Split(string(b), "\n")
-		if len(lines) > 0 {
-			xc.prevLine = strings.TrimSpace(lines[xc.currLine-1])
-		} else {
-			xc.prevLine = ""
-		}
+		s.source = strings.Split(string(b), "\n")
+		s.evalInline()
 	default:
 		log.Printf("%+v", resp)
 	}
@@ -120,156 +189,262 @@ func (xc *Client) handleResponse(resp Response) error {
 	return nil
 }
 
-func (xc *Client) send(s string) (Response, error) {
-	var resp Response
+// handle returns a response handler which passes the response to handleResponse
+// and reports errors to the editor.
+func (s *session) handle(next func(Response)) handler {
+	return func(resp Response) {
+		if err := s.handleResponse(resp); err != nil {
+			log.Println(err)
+			s.xc.Editor.Error(err)
+			return
+		}
+		if next != nil {
+			next(resp)
+		}
+	}
+}
 
-	log.Println("send:", s)
-	_, err := xc.conn.Write([]byte(s))
-	if err != nil {
-		return resp, err
+// command sends DBGp command to the engine. The response is handled on the
+// main loop by handleResponse and then passed to next.
+func (s *session) command(cmd, args string, data []byte, next func(Response)) error {
+	if s.conn == nil {
+		return fmt.Errorf("phpdebug is not started")
 	}
+	_, err := s.conn.send(cmd, args, data, s.handle(next))
+	return err
+}
 
-	b, err := readBlock(xc.conn)
-	if err != nil {
-		return resp, err
+// command sends the command to the current session.
+func (xc *Client) command(cmd, args string, data []byte, next func(Response)) error {
+	if xc.current == nil {
+		return fmt.Errorf("phpdebug is not started")
 	}
+	return xc.current.command(cmd, args, data, next)
+}
 
-	resp, err = unmarshalCommand(b)
-	if err != nil {
-		return resp, err
+// refresh fetches the stack, source, variables and watches of the
+// current location. The source is used for the inline values.
+func (s *session) refresh() {
+	if err := s.command("stack_get", "", nil, nil); err != nil {
+		log.Println(err)
 	}
 
-	if resp.Command != "source" && resp.Command != "stack_get" && resp.Command != "eval" {
-		log.Println("block:", string(b))
+	if err := s.command("source", "-f "+s.currFile, nil, nil); err != nil {
+		log.Println(err)
 	}
 
-	return resp, nil
+	s.depth = 0
+	s.refreshVariables()
+	s.evalWatches()
 }
 
-func (xc *Client) step(stepCmd string) error {
-	s := fmt.Sprintf("%s -i %d\x00", stepCmd, xc.transID)
-	resp, err := xc.send(s)
-	if err != nil {
-		return err
+func (s *session) step(stepCmd string) error {
+	s.filterSteps = false
+	return s.command(stepCmd, "", nil, nil)
+}
+
+// stop ends all sessions. In listen mode the listener stays open for the
+// next session.
+func (xc *Client) stop() {
+	if xc.listening {
+		xc.closeSessions()
+		xc.stopRecording()
+	} else if err := xc.Close(); err != nil {
+		log.Println(err)
+	}
+	xc.started = false
+	xc.Editor.VariablesChanged()
+	xc.Editor.StackChanged()
+	xc.Editor.SessionsChanged()
+	if xc.listening {
+		xc.Editor.Message("debug session ended. Listening for the next connection")
+	} else {
+		xc.Editor.Message("debugger stopped. F8 to start")
 	}
+}
 
-	if err := xc.handleResponse(resp); err != nil {
+// Start starts debug session. It reads the project debug file, starts
+// listening, runs the launch configuration name or the init command and
+// waits for the connection from xdebug in the background. Breakpoints are
+// set once the engine is connected.
+func (xc *Client) Start(name string) error {
+	if err := xc.readConfig(); err != nil {
+		return err
+	}
+	if err := xc.selectLaunch(name); err != nil {
 		return err
 	}
 
-	xc.transID++
-
-	var evalResult bytes.Buffer
-
-	if xc.prevLine != "" && strings.Contains(xc.prevLine, " = ") && strings.HasSuffix(xc.prevLine, ";") && strings.HasPrefix(xc.prevLine, "$") {
-		cc := strings.Split(strings.TrimSpace(xc.prevLine), " = ")
-		eargs := base64.StdEncoding.EncodeToString([]byte("var_export(" + cc[0] + ", TRUE)"))
-		s := fmt.Sprintf("eval -i %d -- %s\x00", xc.transID, eargs)
-		resp, err = xc.send(s)
-		if err != nil {
+	if xc.listening {
+		// the connection is accepted by the persistent listener
+		if err := xc.runLaunch(); err != nil {
 			return err
 		}
-
-		fmt.Fprintf(&evalResult, "\n=== \x1b[31m%s\x1b[0m ===\n", cc[0])
-		dumpProperties(&evalResult, resp.Properties, 0)
-		xc.transID++
+		xc.Editor.Message("waiting for debugger connection on ", xc.listener.Addr())
+		return nil
 	}
 
-	s = fmt.Sprintf("stack_get -i %d\x00", xc.transID)
-	xc.transID++
-	resp, err = xc.send(s)
+	network, addr := listenAddr(xc.Address, xc.Port)
+	l, err := listen(network, addr)
 	if err != nil {
 		return err
 	}
+	xc.listener = l
 
-	if err := xc.handleResponse(resp); err != nil {
+	if err := xc.runLaunch(); err != nil {
+		xc.listener = nil
+		l.Close()
 		return err
 	}
 
-	s = fmt.Sprintf("source -i %d -f %s\x00", xc.transID, xc.currFile)
-	xc.transID++
-	resp, err = xc.send(s)
-	if err != nil {
-		return err
-	}
+	go xc.waitConnection(l, time.Duration(xc.Timeout)*time.Second, xc.IDEKey)
 
-	if err := xc.handleResponse(resp); err != nil {
-		return err
-	}
-	log.Println(evalResult.String())
+	xc.Editor.Message("waiting for debugger connection on ", addr)
 
 	return nil
 }
 
-// Start starts debug session. It starts listening on :9003, makes initial request to php server
-// and accepts connection from xdebug. Then it sets breakpoints from init.yaml file.
-func (xc *Client) Start() error {
-	var err error
-	xc.listener, err = listen()
-	if err != nil {
-		return err
+// waitConnection accepts the engine connection on l and passes it to the
+// main loop.
+func (xc *Client) waitConnection(l net.Listener, timeout time.Duration, idekey string) {
+	conn, init, err := accept(l, timeout, idekey)
+	shell.Post(func() {
+		if xc.listener != l {
+			// stopped while waiting
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			xc.stop()
+			xc.Editor.Error(err)
+			return
+		}
+		xc.connected(conn, init)
+	})
+}
+
+// Listen starts listening for engine connections in the background. Each
+// connection starts a new session, the listener stays open until Unlisten.
+func (xc *Client) Listen() error {
+	if xc.listener != nil {
+		return fmt.Errorf("phpdebug is already listening")
 	}
 
-	if err := xc.readInitFile(); err != nil {
+	if err := xc.readConfig(); err != nil {
 		return err
 	}
 
-	go xc.makeRequest()
-
-	xc.conn, err = accept(xc.listener)
+	network, addr := listenAddr(xc.Address, xc.Port)
+	l, err := listen(network, addr)
 	if err != nil {
 		return err
 	}
+	xc.listener = l
+	xc.listening = true
 
-	if err := xc.processParameters(); err != nil {
-		return err
-	}
+	go xc.acceptLoop(l, xc.IDEKey)
 
-	xc.Editor.Message("started. F5-run F6-step_out F7-step_in F8-step_over F10-exit")
+	xc.Editor.Message("listening for debugger connections on ", addr)
 
 	return nil
 }
 
+// Unlisten closes the listener of the listen mode. The active session, if
+// any, is not affected.
+func (xc *Client) Unlisten() error {
+	if !xc.listening {
+		return fmt.Errorf("phpdebug is not listening")
+	}
+	xc.listening = false
+	if len(xc.sessions) == 0 {
+		xc.started = false
+		return xc.Close()
+	}
+	err := xc.listener.Close()
+	xc.listener = nil
+	return err
+}
+
+// Listening reports whether the listen mode is on.
+func (xc *Client) Listening() bool {
+	return xc.listening
+}
+
+// acceptLoop accepts engine connections on l until it is closed.
+func (xc *Client) acceptLoop(l net.Listener, idekey string) {
+	for {
+		conn, init, err := accept(l, 0, idekey)
+		shell.Post(func() {
+			if xc.listener != l {
+				if conn != nil {
+					conn.Close()
+				}
+				return
+			}
+			if err != nil {
+				log.Println(err)
+				l.Close()
+				xc.listener = nil
+				xc.listening = false
+				if len(xc.sessions) == 0 {
+					xc.started = false
+				}
+				xc.Editor.Error(err)
+				return
+			}
+			xc.started = true
+			xc.connected(conn, init)
+		})
+		if err != nil {
+			return
+		}
+	}
+}
+
 // Close closes accepted and listening sockets.
 func (xc *Client) Close() error {
-	if err := xc.listener.Close(); err != nil {
-		log.Println(err)
-	}
-	if err := xc.conn.Close(); err != nil {
-		log.Println(err)
+	if xc.listener != nil {
+		if err := xc.listener.Close(); err != nil {
+			log.Println(err)
+		}
+		xc.listener = nil
 	}
+	xc.listening = false
+	xc.closeSessions()
+	xc.stopRecording()
 
 	log.Println("Client closed")
 
 	return nil
 }
 
-func (xc *Client) makeRequest() {
-	cmd := exec.Command("sh", "-c", xc.InitCommand)
-	b, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Println(err, xc.InitCommand, "out:", string(b))
-		xc.Editor.Error(err)
-		return
+// closeSessions closes all sessions.
+func (xc *Client) closeSessions() {
+	for _, s := range xc.sessions {
+		s.close()
 	}
-	log.Println("init command:", xc.InitCommand, "\nresult:\n", string(b))
+	xc.sessions = nil
+	xc.current = nil
 }
 
-func dumpProperties(w io.Writer, props []property, depth int) {
-	for i, p := range props {
-		pad := strings.Repeat("    ", depth)
-		v := p.Text
-		if p.Encoding == "base64" {
-			b, err := base64.StdEncoding.DecodeString(v)
-			if err != nil {
-				v = "error:" + err.Error()
-			} else {
-				v = string(b)
-			}
-		}
-		fmt.Fprintf(w, "%s%2d %s %s:%s = %s\n", pad, i+1, p.ClassName, p.Name, p.Type, v)
-		dumpProperties(w, p.Properties, 0)
+// Status returns the debugger state for the statusline: empty if the
+// debugger is off, "listening" between sessions of the listen mode, and
+// "waiting" or the engine status during a session.
+func (xc *Client) Status() string {
+	switch {
+	case xc.current != nil && len(xc.sessions) > 1:
+		return fmt.Sprintf("%s #%d (%d sessions)", xc.current.status, xc.current.id, len(xc.sessions))
+	case xc.current != nil:
+		return xc.current.status
+	case xc.started:
+		return "waiting"
+	case xc.listening:
+		return "listening"
 	}
+	return ""
 }
 
 func (xc *Client) dumpStack(w io.Writer, resp Response) {
@@ -277,54 +452,115 @@ func (xc *Client) dumpStack(w io.Writer, resp Response) {
 
 	for i := len(resp.Stack) - 1; i >= 0; i-- {
 		s := resp.Stack[i]
-		fname := strings.Replace(s.Filename, xc.BasePath, "", 1)
+		fname, err := xc.LocalPath(s.Filename)
+		if err != nil {
+			fname = s.Filename
+		}
 		fmt.Fprintf(w, "%d %s:%d \x1b[32m%s\x1b[0m\n", i, fname, s.Line, s.Where)
 	}
 
 	fmt.Fprintln(w, "=============")
 }
 
-func dumpBreakpoints(br []breakpoint) {
-	fmt.Println("=== breakpoints ===")
-	for i, b := range br {
-		fmt.Printf("%2d %s:%d %s\n", i+1, b.Filename, b.Line, b.Type)
+// SetBreakpoints replaces line breakpoints of the local file. Lines are 1-based.
+// If the session is active the breakpoints are synced to the engine.
+func (xc *Client) SetBreakpoints(fname string, lines []int) {
+	if xc.breakpoints == nil {
+		xc.breakpoints = make(map[string][]int)
+	}
+	if len(lines) == 0 {
+		delete(xc.breakpoints, fname)
+	} else {
+		xc.breakpoints[fname] = lines
 	}
-}
 
-func (xc *Client) readInitFile() error {
-	f, err := os.Open("init.yaml")
-	if err != nil && os.IsNotExist(err) {
-		return nil
+	for _, s := range xc.sessions {
+		if err := s.syncBreakpoints(fname); err != nil {
+			xc.Editor.Error(err)
+		}
 	}
+}
 
-	if err != nil {
-		return fmt.Errorf("init.yaml open. error: %w", err)
+func (xc *Client) hasBreakpoint(fname string, line int) bool {
+	for _, l := range xc.breakpoints[fname] {
+		if l == line {
+			return true
+		}
 	}
+	return false
+}
 
-	defer f.Close()
+// syncBreakpoints sets and removes engine breakpoints of the file to match
+// the breakpoints set in the editor.
+func (s *session) syncBreakpoints(fname string) error {
+	if s.engineBreakpoints == nil {
+		s.engineBreakpoints = make(map[string]map[int]int)
+	}
+	engine := s.engineBreakpoints[fname]
+	if engine == nil {
+		engine = make(map[int]int)
+		s.engineBreakpoints[fname] = engine
+	}
 
-	err = yaml.NewDecoder(f).Decode(xc)
-	if err != nil {
-		return fmt.Errorf("init.yaml decode. error: %w", err)
+	for line, id := range engine {
+		// id is 0 while breakpoint_set is in flight, its handler syncs again
+		if id == 0 || s.xc.hasBreakpoint(fname, line) {
+			continue
+		}
+		delete(engine, line)
+		if err := s.command("breakpoint_remove", fmt.Sprintf("-d %d", id), nil, nil); err != nil {
+			return err
+		}
 	}
 
-	log.Printf("xdebug: %+v", xc)
+	for _, line := range s.xc.breakpoints[fname] {
+		if _, ok := engine[line]; ok {
+			continue
+		}
+		uri, err := s.xc.remoteURI(fname)
+		if err != nil {
+			return err
+		}
+		line := line
+		engine[line] = 0
+		args := fmt.Sprintf("-t line -f %s -n %d", uri, line)
+		err = s.command("breakpoint_set", args, nil, func(resp Response) {
+			engine[line] = resp.ID
+			if !s.xc.hasBreakpoint(fname, line) {
+				if err := s.syncBreakpoints(fname); err != nil {
+					s.xc.Editor.Error(err)
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-func (xc *Client) processParameters() error {
+func (s *session) processParameters() error {
+	for fname := range s.xc.breakpoints {
+		if err := s.syncBreakpoints(fname); err != nil {
+			return fmt.Errorf("set breakpoint. error: %w", err)
+		}
+	}
 
-	for _, b := range xc.Breakpoints {
-		cc := strings.Split(b, " ")
-		s := fmt.Sprintf("breakpoint_set -i %d -t line -f %s%s -n %s\x00", xc.transID, xc.BasePath, cc[0], cc[1])
-		xc.transID++
-		resp, err := xc.send(s)
-		if err != nil {
+	for _, bp := range s.xc.extraBreakpoints {
+		if err := s.setBreakpoint(bp); err != nil {
 			return fmt.Errorf("set breakpoint. error: %w", err)
 		}
-		if err := xc.handleResponse(resp); err != nil {
-			return err
+	}
+
+	for _, b := range s.xc.Breakpoints {
+		cc := strings.Split(b, " ")
+		if len(cc) < 2 {
+			return fmt.Errorf("invalid breakpoint %q, expected: FILE LINE", b)
+		}
+		args := fmt.Sprintf("-t line -f %s%s -n %s", s.xc.BasePath, cc[0], cc[1])
+		if err := s.command("breakpoint_set", args, nil, nil); err != nil {
+			return fmt.Errorf("set breakpoint. error: %w", err)
 		}
 	}
 
@@ -332,7 +568,8 @@ func (xc *Client) processParameters() error {
 }
 
 // ProcessCommand handles commands from the editor. It interacts with the editor by
-// calling Editor interface methods.
+// calling Editor interface methods. Responses from the engine are handled
+// asynchronously on the main loop.
 func (xc *Client) ProcessCommand(args []string) error {
 	var t string
 
@@ -340,6 +577,31 @@ func (xc *Client) ProcessCommand(args []string) error {
 		t = args[0]
 	}
 
+	switch t {
+	case "proxyinit":
+		return xc.ProxyInit()
+	case "proxystop":
+		return xc.ProxyStop()
+	}
+
+	if t == "listen" {
+		if xc.listening {
+			if err := xc.Unlisten(); err != nil {
+				return err
+			}
+			xc.Editor.Message("stopped listening for debugger connections")
+			return nil
+		}
+		return xc.Listen()
+	}
+
+	if t == "demo" {
+		return xc.demoCmd(args[1:])
+	}
+	if t == "replay" {
+		return xc.replayCmd(args[1:])
+	}
+
 	if !xc.started && (t == "s" || t == "n" || t == "c") {
 		t = "start"
 	}
@@ -348,93 +610,104 @@ func (xc *Client) ProcessCommand(args []string) error {
 		return fmt.Errorf("phpdebug is not started")
 	}
 
+	// session commands take the session ID
+	if (t == "session" || t == "stop" || t == "detach") && len(args) > 1 {
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid session %q", args[1])
+		}
+		switch t {
+		case "session":
+			return xc.SelectSession(id)
+		case "stop":
+			return xc.StopSession(id)
+		default:
+			return xc.DetachSession(id)
+		}
+	}
+
+	s := xc.current
+	if s == nil && xc.started && t != "stop" {
+		return fmt.Errorf("phpdebug is waiting for the debugger connection")
+	}
+
+	if s != nil && s.status == "running" && t != "break" && t != "detach" && t != "stop" {
+		return fmt.Errorf("phpdebug is running. Use break to interrupt")
+	}
+
 	switch t {
 	case "start":
 		if xc.started {
 			return fmt.Errorf("phpdebug already started")
 		}
-		if err := xc.Start(); err != nil {
-			return err
+		name := ""
+		if args[0] == "start" && len(args) > 1 {
+			name = args[1]
 		}
-		xc.started = true
-		if err := xc.step("step_into"); err != nil {
+		if err := xc.Start(name); err != nil {
 			return err
 		}
+		xc.started = true
 	case "stop":
-		if err := xc.Close(); err != nil {
-			return err
-		}
-		xc.started = false
+		xc.stop()
 	case "so":
-		if err := xc.step("step_out"); err != nil {
+		if err := s.step("step_out"); err != nil {
 			return err
 		}
 	case "s":
-		if err := xc.step("step_into"); err != nil {
+		if err := s.stepInto(); err != nil {
 			return err
 		}
 	case "n":
-		if err := xc.step("step_over"); err != nil {
+		if err := s.step("step_over"); err != nil {
 			return err
 		}
 	case "c":
-		s := fmt.Sprintf("run -i %d\x00", xc.transID)
-		resp, err := xc.send(s)
-		if err != nil {
+		if err := s.step("run"); err != nil {
 			return err
 		}
-
-		if err := xc.handleResponse(resp); err != nil {
-			return err
+		s.status = "running"
+		xc.Editor.SessionsChanged()
+		xc.Editor.Message("running. F9 to break")
+	case "break":
+		if s.status != "running" {
+			return fmt.Errorf("phpdebug is not running")
 		}
-	case "b":
-		s := fmt.Sprintf("breakpoint_set -i %d -t line -f %s -n %d\x00", xc.transID, xc.currFile, xc.currLine)
-		xc.transID++
-		resp, err := xc.send(s)
-		if err != nil {
-			return err
+		if !s.supportsAsync() {
+			return fmt.Errorf("the engine does not support break while running")
 		}
-		if err := xc.handleResponse(resp); err != nil {
+		if err := xc.command("break", "", nil, nil); err != nil {
 			return err
 		}
-		s = fmt.Sprintf("breakpoint_list -i %d\x00", xc.transID)
-		xc.transID++
-		resp, err = xc.send(s)
-		if err != nil {
+	case "detach":
+		if err := xc.command("detach", "", nil, nil); err != nil {
 			return err
 		}
-		if err := xc.handleResponse(resp); err != nil {
-			return err
+	case "b":
+		// a line breakpoint at the break by default, the location of line
+		// and cond breakpoints defaults to it
+		bpArgs := args[1:]
+		if len(bpArgs) == 0 {
+			bpArgs = []string{"line"}
 		}
-	case "bl":
-		s := fmt.Sprintf("breakpoint_list -i %d\x00", xc.transID)
-		xc.transID++
-		resp, err := xc.send(s)
+		fname, err := xc.LocalPath(s.currFile)
 		if err != nil {
-			xc.Editor.Error(err)
-			return err
+			fname = ""
 		}
-		if err := xc.handleResponse(resp); err != nil {
+		bp, err := debugger.ParseBreakpoint(bpArgs, fname, s.currLine)
+		if err != nil {
 			return err
 		}
-	case "e":
-		args := strings.Join(args[1:], " ")
-		eargs := base64.StdEncoding.EncodeToString([]byte("var_export(" + args + ", TRUE)"))
-		s := fmt.Sprintf("eval -i %d -- %s\x00", xc.transID, eargs)
-		resp, err := xc.send(s)
-		if err != nil {
+		if err := xc.AddBreakpoint(bp); err != nil {
 			return err
 		}
-		var b bytes.Buffer
-		dumpProperties(&b, resp.Properties, 0)
-		log.Println("=============== eval\n\x1b[31m", args, "\x1b[0m\n", b.String())
-	default:
-		s := fmt.Sprintf("%s -i %d\x00", t, xc.transID)
-		resp, err := xc.send(s)
-		if err != nil {
+		xc.Editor.Message("breakpoint added: ", bp.String())
+	case "bl":
+		if err := xc.command("breakpoint_list", "", nil, nil); err != nil {
 			return err
 		}
-		if err := xc.handleResponse(resp); err != nil {
+	default:
+		if err := xc.command(t, strings.Join(args[1:], " "), nil, nil); err != nil {
 			return err
 		}
 	}
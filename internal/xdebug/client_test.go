@@ -0,0 +1,180 @@
+package xdebug
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// testEditor records messages and errors of the client.
+type testEditor struct {
+	messages []string
+	errors   []string
+	output   map[int]string
+	sources  map[string]string
+}
+
+func (e *testEditor) OpenCmd([]string)           {}
+func (e *testEditor) GotoCmd([]string)           {}
+func (e *testEditor) Message(msg ...interface{}) { e.messages = append(e.messages, sprint(msg)) }
+func (e *testEditor) Error(msg ...interface{})   { e.errors = append(e.errors, sprint(msg)) }
+func (e *testEditor) VariablesChanged()          {}
+func (e *testEditor) WatchesChanged()            {}
+func (e *testEditor) StackChanged()              {}
+func (e *testEditor) SessionsChanged()           {}
+func (e *testEditor) LocationChanged()           {}
+
+func (e *testEditor) OpenSource(uri, src string, line int) {
+	if e.sources == nil {
+		e.sources = make(map[string]string)
+	}
+	e.sources[uri] = src
+}
+
+func (e *testEditor) Output(session int, text string) {
+	if e.output == nil {
+		e.output = make(map[int]string)
+	}
+	e.output[session] += text
+}
+
+// connectEngine reads the commands sent to a new session up to the first
+// run command.
+func connectEngine(t *testing.T, engine net.Conn) {
+	for i, name := range engineFeatures {
+		assert.Equal(t, fmt.Sprintf("feature_get -i %d -n %s", i+1, name), readCommand(t, engine))
+	}
+	assert.Equal(t, "feature_set -i 5 -n resolved_breakpoints -v 1", readCommand(t, engine))
+	assert.Equal(t, "stdout -i 6 -c 1", readCommand(t, engine))
+	assert.Equal(t, "stderr -i 7 -c 1", readCommand(t, engine))
+	assert.Equal(t, "run -i 8", readCommand(t, engine))
+}
+
+func sprint(msg []interface{}) string {
+	return strings.TrimSpace(fmt.Sprint(msg...))
+}
+
+func TestListenMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xdebug")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "dbgp.sock")
+
+	ed := &testEditor{}
+	xc := &Client{Options: Config{Address: "unix:" + sock}, Editor: ed}
+
+	assert.Equal(t, "", xc.Status())
+	assert.NoError(t, xc.ProcessCommand([]string{"listen"}))
+	assert.Equal(t, "listening", xc.Status())
+
+	// two sessions on the same listener
+	for i := 0; i < 2; i++ {
+		engine, err := net.Dial("unix", sock)
+		assert.NoError(t, err)
+		writePacket(t, engine, `<init idekey="x" fileuri="file:///var/www/index.php"/>`)
+		runJob(t)
+
+		connectEngine(t, engine)
+		assert.Equal(t, "running", xc.Status())
+
+		writePacket(t, engine, `<stream type="stdout" encoding="base64">aGVsbG8K</stream>`)
+		runJob(t)
+		assert.Equal(t, "hello\n", ed.output[i+1])
+
+		writePacket(t, engine, `<response command="run" transaction_id="8" status="stopping"/>`)
+		runJob(t)
+		assert.Equal(t, "listening", xc.Status())
+
+		// connection closed by stop
+		runJob(t)
+		engine.Close()
+	}
+
+	assert.NoError(t, xc.ProcessCommand([]string{"listen"}))
+	assert.Equal(t, "", xc.Status())
+	// accept error of the closed listener
+	runJob(t)
+	_, err = os.Stat(sock)
+	assert.True(t, os.IsNotExist(err))
+	assert.Empty(t, ed.errors)
+}
+
+func TestSessions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xdebug")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "dbgp.sock")
+
+	ed := &testEditor{}
+	xc := &Client{Options: Config{Address: "unix:" + sock}, Editor: ed}
+	assert.NoError(t, xc.Listen())
+
+	var engines []net.Conn
+	for _, script := range []string{"a.php", "b.php"} {
+		engine, err := net.Dial("unix", sock)
+		assert.NoError(t, err)
+		defer engine.Close()
+		writePacket(t, engine, `<init idekey="x" fileuri="file:///var/www/`+script+`"/>`)
+		runJob(t)
+		connectEngine(t, engine)
+		engines = append(engines, engine)
+	}
+
+	// a break of the background session does not switch sessions
+	writePacket(t, engines[1], `<response command="run" transaction_id="8" status="break"><xdebug:message filename="file:///var/www/b.php" lineno="3"/></response>`)
+	runJob(t)
+	assert.Equal(t, "session 2: break at b.php:3", ed.messages[len(ed.messages)-1])
+	assert.Equal(t, []debugger.SessionInfo{
+		{ID: 1, IDEKey: "x", File: "file:///var/www/a.php", Status: "running", Current: true},
+		{ID: 2, IDEKey: "x", File: "file:///var/www/b.php", Status: "break"},
+	}, xc.Sessions())
+	assert.Equal(t, "running #1 (2 sessions)", xc.Status())
+
+	// commands go to the selected session
+	assert.NoError(t, xc.ProcessCommand([]string{"session", "2"}))
+	// the script has no local file, its source is opened
+	assert.Equal(t, "source -i 9 -f file:///var/www/b.php", readCommand(t, engines[1]))
+	assert.Equal(t, "stack_get -i 10", readCommand(t, engines[1]))
+	assert.Equal(t, "source -i 11 -f file:///var/www/b.php", readCommand(t, engines[1]))
+	assert.Equal(t, "context_names -i 12 -d 0", readCommand(t, engines[1]))
+	writePacket(t, engines[1], `<response command="source" transaction_id="9" encoding="base64"><![CDATA[PD9waHAK]]></response>`)
+	runJob(t)
+	assert.Equal(t, map[string]string{"file:///var/www/b.php": "<?php\n"}, ed.sources)
+	assert.NoError(t, xc.ProcessCommand([]string{"n"}))
+	assert.Equal(t, "step_over -i 13", readCommand(t, engines[1]))
+
+	// stopping the other session keeps the current one
+	assert.NoError(t, xc.ProcessCommand([]string{"stop", "1"}))
+	_, err = engines[0].Read(make([]byte, 1))
+	assert.Error(t, err)
+	runJob(t)
+	assert.Equal(t, "break", xc.Status())
+	assert.Len(t, xc.Sessions(), 1)
+
+	assert.NoError(t, xc.ProcessCommand([]string{"stop"}))
+	runJob(t)
+	assert.Equal(t, "listening", xc.Status())
+
+	assert.NoError(t, xc.Unlisten())
+	runJob(t)
+	assert.Empty(t, ed.errors)
+}
+
+// testSession returns a client with a current session at a break connected
+// to the returned engine end of a pipe.
+func testSession(t *testing.T) (*Client, *session, net.Conn) {
+	ide, engine := net.Pipe()
+	xc := &Client{Editor: &testEditor{}, started: true}
+	s := &session{xc: xc, id: 1, status: "break", conn: newTransport(ide)}
+	s.conn.start()
+	xc.sessions = []*session{s}
+	xc.current = s
+	return xc, s, engine
+}
@@ -0,0 +1,84 @@
+package xdebug
+
+import (
+	"fmt"
+	"log"
+)
+
+// engineFeatures are read with feature_get when a session starts.
+var engineFeatures = []string{"language_name", "language_version", "protocol_version", "supports_async"}
+
+// negotiateFeatures reads the engine features and sets the limits of the
+// client. Limits of 0 keep the engine defaults.
+func (s *session) negotiateFeatures() {
+	s.features = make(map[string]string)
+	for _, name := range engineFeatures {
+		name := name
+		_, err := s.conn.send("feature_get", "-n "+name, nil, func(resp Response) {
+			if resp.Error.Code != 0 || resp.Support != 1 {
+				log.Printf("feature_get %s: not supported", name)
+				return
+			}
+			s.features[name] = resp.Text
+			s.xc.Editor.SessionsChanged()
+		})
+		if err != nil {
+			log.Println(err)
+			return
+		}
+	}
+
+	// breakpoint_resolved notifications are sent when the file of a
+	// breakpoint is loaded
+	_, err := s.conn.send("feature_set", "-n resolved_breakpoints -v 1", nil, func(resp Response) {
+		if resp.Error.Code != 0 || resp.Success != 1 {
+			log.Println("feature_set resolved_breakpoints: not supported")
+		}
+	})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	limits := []struct {
+		name  string
+		value int
+	}{
+		{"max_children", s.xc.MaxChildren},
+		{"max_data", s.xc.MaxData},
+		{"max_depth", s.xc.MaxDepth},
+	}
+	for _, l := range limits {
+		if l.value <= 0 {
+			continue
+		}
+		l := l
+		args := fmt.Sprintf("-n %s -v %d", l.name, l.value)
+		_, err := s.conn.send("feature_set", args, nil, func(resp Response) {
+			if resp.Error.Code != 0 || resp.Success != 1 {
+				log.Printf("feature_set %s: error %d: %s", args, resp.Error.Code, resp.Error.Message.Text)
+				s.xc.Editor.Error(fmt.Sprintf("session %d: the engine does not support %s", s.id, l.name))
+			}
+		})
+		if err != nil {
+			log.Println(err)
+			return
+		}
+	}
+}
+
+// supportsAsync returns true unless the engine reported that it does not
+// accept commands like break while the script is running.
+func (s *session) supportsAsync() bool {
+	v, ok := s.features["supports_async"]
+	return !ok || v == "1"
+}
+
+// language returns the language name and version of the engine.
+func (s *session) language() string {
+	lang := s.features["language_name"]
+	if v := s.features["language_version"]; v != "" && lang != "" {
+		lang += " " + v
+	}
+	return lang
+}
@@ -0,0 +1,42 @@
+package xdebug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatures(t *testing.T) {
+	xc, s, engine := testSession(t)
+	defer engine.Close()
+	cmds := engineCommands(engine)
+	xc.MaxChildren = 100
+	xc.MaxDepth = 2
+
+	s.negotiateFeatures()
+	assert.Equal(t, "feature_get -i 1 -n language_name", nextCommand(t, cmds))
+	assert.Equal(t, "feature_get -i 2 -n language_version", nextCommand(t, cmds))
+	assert.Equal(t, "feature_get -i 3 -n protocol_version", nextCommand(t, cmds))
+	assert.Equal(t, "feature_get -i 4 -n supports_async", nextCommand(t, cmds))
+	assert.Equal(t, "feature_set -i 5 -n resolved_breakpoints -v 1", nextCommand(t, cmds))
+	assert.Equal(t, "feature_set -i 6 -n max_children -v 100", nextCommand(t, cmds))
+	assert.Equal(t, "feature_set -i 7 -n max_depth -v 2", nextCommand(t, cmds))
+
+	// break is allowed until the engine reports no async support
+	assert.True(t, s.supportsAsync())
+	writePacket(t, engine, `<response command="feature_get" transaction_id="1" feature_name="language_name" supported="1"><![CDATA[PHP]]></response>`)
+	writePacket(t, engine, `<response command="feature_get" transaction_id="2" feature_name="language_version" supported="1"><![CDATA[8.2.1]]></response>`)
+	writePacket(t, engine, `<response command="feature_get" transaction_id="4" feature_name="supports_async" supported="1"><![CDATA[0]]></response>`)
+	for i := 0; i < 3; i++ {
+		runJob(t)
+	}
+	assert.Equal(t, "PHP 8.2.1", xc.Sessions()[0].Language)
+	assert.False(t, s.supportsAsync())
+
+	s.status = "running"
+	assert.EqualError(t, xc.ProcessCommand([]string{"break"}), "the engine does not support break while running")
+
+	writePacket(t, engine, `<response command="feature_set" transaction_id="7" feature="max_depth" success="0"/>`)
+	runJob(t)
+	assert.Equal(t, []string{"session 1: the engine does not support max_depth"}, xc.Editor.(*testEditor).errors)
+}
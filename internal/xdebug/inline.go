@@ -0,0 +1,127 @@
+package xdebug
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// maxExecuted is the number of executed lines with inline values
+const maxExecuted = 20
+
+// assignRegexp matches the variable paths assigned by =, .= etc.
+var assignRegexp = regexp.MustCompile(`(\$\w+(?:(?:->|::)\$?\w+|\[[^\]=]*\])*)\s*(?:[-+*/.%|&^]|\*\*|\?\?)?=(?:[^=>]|$)`)
+
+// foreachRegexp matches the key and value variables of a foreach loop
+var foreachRegexp = regexp.MustCompile(`\bforeach\s*\(.*\bas\s+(?:(\$\w+)\s*=>\s*)?&?(\$\w+)`)
+
+// assignedVariables returns the variables assigned on the source line.
+func assignedVariables(line string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	if m := foreachRegexp.FindStringSubmatch(line); m != nil {
+		add(m[1])
+		add(m[2])
+	}
+	for _, m := range assignRegexp.FindAllStringSubmatch(line, -1) {
+		add(m[1])
+	}
+	return names
+}
+
+// Location returns the engine file URI and the line the current session is
+// stopped at, or an empty URI if it is not stopped.
+func (xc *Client) Location() (string, int) {
+	s := xc.current
+	if s == nil || s.status != "break" {
+		return "", 0
+	}
+	return s.currFile, s.currLine
+}
+
+// InlineValues returns the current values of the variables assigned on the
+// lines executed in the current function so far, by line of the file of
+// Location. Values of variables which could not be fetched are nil.
+func (xc *Client) InlineValues() map[int][]*debugger.Property {
+	if xc.current == nil || xc.current.status != "break" {
+		return nil
+	}
+	return xc.current.inline
+}
+
+// trackExecuted records the line of the previous break as executed when
+// the engine stops at the next line of the same file.
+func (s *session) trackExecuted(file string) {
+	if file != s.currFile {
+		s.executed = nil
+		return
+	}
+	if s.currLine <= 0 {
+		return
+	}
+
+	for i, l := range s.executed {
+		if l == s.currLine {
+			s.executed = append(s.executed[:i], s.executed[i+1:]...)
+			break
+		}
+	}
+	s.executed = append(s.executed, s.currLine)
+	if len(s.executed) > maxExecuted {
+		s.executed = s.executed[len(s.executed)-maxExecuted:]
+	}
+}
+
+// enterFunction forgets the executed lines when the break is in another
+// function than the previous one.
+func (s *session) enterFunction(where string) {
+	if where != s.where {
+		s.where = where
+		s.executed = nil
+	}
+}
+
+// evalInline fetches the variables assigned on the executed lines. The
+// values are fetched with property_get, so no code of the script is run.
+func (s *session) evalInline() {
+	s.inline = make(map[int][]*debugger.Property)
+	inline := s.inline
+	for _, line := range s.executed {
+		if line > len(s.source) {
+			continue
+		}
+		names := assignedVariables(s.source[line-1])
+		if len(names) == 0 {
+			continue
+		}
+
+		values := make([]*debugger.Property, len(names))
+		inline[line] = values
+		for i, name := range names {
+			i, name := i, name
+			args := fmt.Sprintf("-n %s -d 0", quoteArg(name))
+			_, err := s.conn.send("property_get", args, nil, func(resp Response) {
+				if resp.Error.Code != 0 || len(resp.Properties) == 0 {
+					return
+				}
+				values[i] = newProperty(resp.Properties[0], 0, 0)
+				values[i].FullName = name
+				s.xc.Editor.LocationChanged()
+			})
+			if err != nil {
+				log.Println(err)
+				return
+			}
+		}
+	}
+	s.xc.Editor.LocationChanged()
+}
@@ -0,0 +1,64 @@
+package xdebug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignedVariables(t *testing.T) {
+	tests := []struct {
+		line  string
+		names []string
+	}{
+		{`$a = 1;`, []string{"$a"}},
+		{`$a .= "x"; $b ??= $c;`, []string{"$a", "$b"}},
+		{`$this->items['k'] = $a == $b;`, []string{"$this->items['k']"}},
+		{`$a = $b = f();`, []string{"$a", "$b"}},
+		{`foreach ($items as $k => $v) {`, []string{"$k", "$v"}},
+		{`foreach ($items as &$v) {`, []string{"$v"}},
+		{`if ($a === $b || $c <= $d || $e != 1) {`, nil},
+		{`return [$k => $v];`, nil},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.names, assignedVariables(test.line), test.line)
+	}
+}
+
+func TestInlineValues(t *testing.T) {
+	xc, s, engine := testSession(t)
+	defer engine.Close()
+
+	s.currFile, s.currLine, s.where = "file:///a.php", 2, "{main}"
+	s.trackExecuted("file:///a.php")
+	s.currLine = 3
+	s.trackExecuted("file:///a.php")
+	s.currLine = 4
+	s.enterFunction("{main}")
+	assert.Equal(t, []int{2, 3}, s.executed)
+
+	s.source = []string{"<?php", "$a = 1;", "f($a);", "$b = $a;"}
+	go s.evalInline()
+	assert.Equal(t, `property_get -i 1 -n "$a" -d 0`, readCommand(t, engine))
+	writePacket(t, engine, `<response command="property_get" transaction_id="1"><property name="$a" fullname="$a" type="int"><![CDATA[1]]></property></response>`)
+	runJob(t)
+
+	file, line := xc.Location()
+	assert.Equal(t, "file:///a.php", file)
+	assert.Equal(t, 4, line)
+	values := xc.InlineValues()
+	assert.Len(t, values, 1)
+	assert.Equal(t, "$a", values[2][0].FullName)
+	assert.Equal(t, "int 1", values[2][0].Summary())
+
+	// executed lines of another function are not shown
+	s.enterFunction("f")
+	assert.Empty(t, s.executed)
+	s.trackExecuted("file:///b.php")
+	assert.Empty(t, s.executed)
+
+	s.status = "running"
+	file, _ = xc.Location()
+	assert.Equal(t, "", file)
+	assert.Nil(t, xc.InlineValues())
+}
@@ -0,0 +1,268 @@
+package xdebug
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+	"github.com/zyedidia/micro/v2/internal/shell"
+	"gopkg.in/yaml.v2"
+)
+
+// Launch is a named launch configuration of the project debug file. It
+// starts the script with the debugger after the listener is open.
+type Launch struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"` // cli, http, phpunit or command, guessed from the fields if empty
+
+	Script  string   `yaml:"script"`  // cli: PHP script
+	Args    []string `yaml:"args"`    // cli and phpunit: arguments of the script
+	URL     string   `yaml:"url"`     // http: requested URL
+	Method  string   `yaml:"method"`  // http: request method, GET if empty
+	Data    string   `yaml:"data"`    // http: request body
+	Test    string   `yaml:"test"`    // phpunit: test file or directory
+	Filter  string   `yaml:"filter"`  // phpunit: --filter pattern
+	PHPUnit string   `yaml:"phpunit"` // phpunit: runner, vendor/bin/phpunit if empty
+	Command string   `yaml:"command"` // command: shell command
+	PHP     string   `yaml:"php"`     // PHP binary, php if empty
+
+	Cwd          string            `yaml:"cwd"` // working directory, relative to the project root
+	Env          map[string]string `yaml:"env"`
+	PathMappings []PathMapping     `yaml:"path_mappings"` // searched before the mappings of the project
+	StopOnEntry  *bool             `yaml:"stop_on_entry"` // overrides break_first
+}
+
+// LaunchTypes are the types of launch configurations.
+var LaunchTypes = []string{"cli", "http", "phpunit", "command"}
+
+func debugFile(root string) string {
+	return filepath.Join(root, debugger.ProjectDir, "debug.yaml")
+}
+
+// LaunchNames returns the names of the launch configurations of the
+// project for completion. Errors are only logged.
+func LaunchNames(root string) []string {
+	var cfg struct {
+		Launches []Launch `yaml:"configurations"`
+	}
+	b, err := ioutil.ReadFile(debugFile(root))
+	if err != nil {
+		return nil
+	}
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	var names []string
+	for _, l := range cfg.Launches {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// readConfig sets the configuration of the client to the options overridden
+// by the debug file of the project. Projects without .micro/debug.yaml may
+// have the older init.yaml in the root.
+func (xc *Client) readConfig() error {
+	xc.Config = xc.Options
+	fname := debugFile(xc.root())
+	b, err := ioutil.ReadFile(fname)
+	if err != nil && os.IsNotExist(err) {
+		fname = filepath.Join(xc.root(), "init.yaml")
+		b, err = ioutil.ReadFile(fname)
+	}
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s read. error: %w", filepath.Base(fname), err)
+	}
+
+	// keys removed from the file get the options again
+	cfg := xc.Options
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("%s decode. error: %w", filepath.Base(fname), err)
+	}
+	xc.Config = cfg
+
+	log.Printf("xdebug: %+v", xc.Config)
+
+	return nil
+}
+
+// selectLaunch selects the launch configuration used by the next session.
+// Without a name the init command is run, if any.
+func (xc *Client) selectLaunch(name string) error {
+	xc.launch = nil
+	if name == "" {
+		return nil
+	}
+	for i := range xc.Launches {
+		if xc.Launches[i].Name == name {
+			xc.launch = &xc.Launches[i]
+			return nil
+		}
+	}
+	return fmt.Errorf("no launch configuration %q in %s", name, debugFile(xc.root()))
+}
+
+// breakFirst returns true if sessions break on the first line.
+func (xc *Client) breakFirst() bool {
+	if xc.launch != nil && xc.launch.StopOnEntry != nil {
+		return *xc.launch.StopOnEntry
+	}
+	return xc.BreakFirst
+}
+
+// runLaunch starts the script of the selected launch configuration or the
+// init command in the background.
+func (xc *Client) runLaunch() error {
+	var cmd *exec.Cmd
+	if xc.launch != nil {
+		var err error
+		cmd, err = xc.launch.command(xc.root(), xc.engineArgs(), xc.sessionKey())
+		if err != nil {
+			return err
+		}
+	} else if xc.InitCommand != "" {
+		cmd = exec.Command("sh", "-c", xc.InitCommand)
+		cmd.Dir = xc.root()
+	} else {
+		return nil
+	}
+
+	go func() {
+		b, err := cmd.CombinedOutput()
+		if err != nil {
+			log.Println(err, cmd.Args, "out:", string(b))
+			shell.Post(func() { xc.Editor.Error(fmt.Sprintf("launch: %v", err)) })
+			return
+		}
+		log.Println("launch:", cmd.Args, "\nresult:\n", string(b))
+	}()
+	return nil
+}
+
+// sessionKey returns the IDE key the launched scripts send to the debugger.
+func (xc *Client) sessionKey() string {
+	if xc.IDEKey != "" {
+		return xc.IDEKey
+	}
+	return "micro"
+}
+
+// engineArgs returns the php options which make Xdebug connect to the
+// listener of the client.
+func (xc *Client) engineArgs() []string {
+	args := []string{
+		"-dxdebug.mode=debug",
+		"-dxdebug.start_with_request=yes",
+		"-dxdebug.idekey=" + xc.sessionKey(),
+	}
+
+	network, addr := listenAddr(xc.Address, xc.Port)
+	if network == "unix" {
+		return append(args, "-dxdebug.client_host=unix://"+addr)
+	}
+	host, port, _ := net.SplitHostPort(addr)
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return append(args, "-dxdebug.client_host="+host, "-dxdebug.client_port="+port)
+}
+
+// command returns the command which runs the launch configuration with the
+// debugger. engineArgs are the php options enabling Xdebug and idekey is
+// the key sent by HTTP requests.
+func (l *Launch) command(root string, engineArgs []string, idekey string) (*exec.Cmd, error) {
+	php := l.PHP
+	if php == "" {
+		php = "php"
+	}
+
+	t := l.Type
+	if t == "" {
+		switch {
+		case l.Command != "":
+			t = "command"
+		case l.URL != "":
+			t = "http"
+		case l.Test != "" || l.Filter != "":
+			t = "phpunit"
+		default:
+			t = "cli"
+		}
+	}
+
+	var args []string
+	switch t {
+	case "cli":
+		if l.Script == "" {
+			return nil, fmt.Errorf("launch %s: script expected", l.Name)
+		}
+		args = append(append([]string{php}, engineArgs...), l.Script)
+		args = append(args, l.Args...)
+	case "phpunit":
+		runner := l.PHPUnit
+		if runner == "" {
+			runner = filepath.Join("vendor", "bin", "phpunit")
+		}
+		args = append(append([]string{php}, engineArgs...), runner)
+		if l.Filter != "" {
+			args = append(args, "--filter", l.Filter)
+		}
+		args = append(args, l.Args...)
+		if l.Test != "" {
+			args = append(args, l.Test)
+		}
+	case "http":
+		if l.URL == "" {
+			return nil, fmt.Errorf("launch %s: url expected", l.Name)
+		}
+		args = []string{"curl", "-sS", "-b", "XDEBUG_SESSION=" + idekey}
+		if l.Method != "" {
+			args = append(args, "-X", l.Method)
+		}
+		if l.Data != "" {
+			args = append(args, "--data", l.Data)
+		}
+		args = append(args, l.URL)
+	case "command":
+		if l.Command == "" {
+			return nil, fmt.Errorf("launch %s: command expected", l.Name)
+		}
+		args = []string{"sh", "-c", l.Command}
+	default:
+		return nil, fmt.Errorf("launch %s: unknown type %q", l.Name, t)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = root
+	if l.Cwd != "" {
+		cmd.Dir = l.Cwd
+		if !filepath.IsAbs(l.Cwd) {
+			cmd.Dir = filepath.Join(root, l.Cwd)
+		}
+	}
+
+	if len(l.Env) > 0 {
+		var keys []string
+		for k := range l.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		cmd.Env = os.Environ()
+		for _, k := range keys {
+			cmd.Env = append(cmd.Env, k+"="+l.Env[k])
+		}
+	}
+
+	return cmd, nil
+}
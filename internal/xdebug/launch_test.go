@@ -0,0 +1,102 @@
+package xdebug
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+func TestLaunchCommand(t *testing.T) {
+	xc := &Client{Config: Config{Address: "0.0.0.0", Port: 9100}}
+	engine := xc.engineArgs()
+	assert.Equal(t, []string{
+		"-dxdebug.mode=debug",
+		"-dxdebug.start_with_request=yes",
+		"-dxdebug.idekey=micro",
+		"-dxdebug.client_host=127.0.0.1",
+		"-dxdebug.client_port=9100",
+	}, engine)
+
+	tests := []struct {
+		launch Launch
+		args   []string
+	}{
+		{Launch{Script: "bin/console", Args: []string{"cache:clear"}}, append(append([]string{"php"}, engine...), "bin/console", "cache:clear")},
+		{Launch{Test: "tests/UserTest.php", Filter: "testName"}, append(append([]string{"php"}, engine...), "vendor/bin/phpunit", "--filter", "testName", "tests/UserTest.php")},
+		{Launch{URL: "http://localhost/api", Method: "POST", Data: "a=1"}, []string{"curl", "-sS", "-b", "XDEBUG_SESSION=micro", "-X", "POST", "--data", "a=1", "http://localhost/api"}},
+		{Launch{Command: "make serve"}, []string{"sh", "-c", "make serve"}},
+	}
+	for _, test := range tests {
+		cmd, err := test.launch.command("/srv/app", engine, "micro")
+		assert.NoError(t, err)
+		assert.Equal(t, test.args, cmd.Args)
+		assert.Equal(t, "/srv/app", cmd.Dir)
+		assert.Nil(t, cmd.Env)
+	}
+
+	l := Launch{Name: "x", Script: "a.php", Cwd: "public", Env: map[string]string{"APP_ENV": "test"}}
+	cmd, err := l.command("/srv/app", engine, "micro")
+	assert.NoError(t, err)
+	assert.Equal(t, "/srv/app/public", cmd.Dir)
+	assert.Equal(t, "APP_ENV=test", cmd.Env[len(cmd.Env)-1])
+
+	_, err = (&Launch{Name: "x", Type: "http"}).command("/srv/app", engine, "micro")
+	assert.EqualError(t, err, "launch x: url expected")
+	_, err = (&Launch{Name: "x", Type: "gdb"}).command("/srv/app", engine, "micro")
+	assert.EqualError(t, err, `launch x: unknown type "gdb"`)
+}
+
+func TestReadConfig(t *testing.T) {
+	root, err := ioutil.TempDir("", "xdebug")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	xc := &Client{Root: root, Options: Config{BreakFirst: true}}
+	assert.NoError(t, xc.readConfig())
+	assert.Empty(t, xc.Launches)
+	assert.Nil(t, LaunchNames(root))
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, debugger.ProjectDir), os.ModePerm))
+	cfg := `
+path_mappings:
+  - remote: /var/www
+    local: .
+configurations:
+  - name: cli
+    script: index.php
+  - name: docker
+    url: http://localhost:8080/
+    stop_on_entry: false
+    path_mappings:
+      - remote: /app
+        local: src
+`
+	assert.NoError(t, ioutil.WriteFile(debugFile(root), []byte(cfg), 0644))
+	assert.Equal(t, []string{"cli", "docker"}, LaunchNames(root))
+
+	assert.NoError(t, xc.readConfig())
+	assert.NoError(t, xc.selectLaunch("docker"))
+	assert.False(t, xc.breakFirst())
+	assert.Equal(t, []PathMapping{
+		{Remote: "/app", Local: filepath.Join(root, "src")},
+		{Remote: "/var/www", Local: root},
+	}, xc.mappings())
+
+	assert.NoError(t, xc.selectLaunch(""))
+	assert.True(t, xc.breakFirst())
+	assert.Error(t, xc.selectLaunch("web"))
+
+	// keys removed from the file get the options again
+	assert.NoError(t, ioutil.WriteFile(debugFile(root), []byte("break_first: false\n"), 0644))
+	assert.NoError(t, xc.readConfig())
+	assert.False(t, xc.BreakFirst)
+	assert.Empty(t, xc.Launches)
+	assert.Empty(t, xc.PathMappings)
+	assert.NoError(t, ioutil.WriteFile(debugFile(root), []byte("init: make\n"), 0644))
+	assert.NoError(t, xc.readConfig())
+	assert.True(t, xc.BreakFirst)
+}
@@ -0,0 +1,153 @@
+package xdebug
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// PathMapping maps a directory on the machine running the engine (e.g. a
+// Docker container) to a local directory. Relative local directories are
+// relative to the project root.
+type PathMapping struct {
+	Remote string `yaml:"remote"` // remote path or file:// URI
+	Local  string `yaml:"local"`
+}
+
+// uriToPath decodes the file URI to a path.
+func uriToPath(uri string) (string, error) {
+	if !strings.HasPrefix(uri, "file://") {
+		return "", fmt.Errorf("%s: not a file URI", uri)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", uri, err)
+	}
+	p := u.Path
+	// file:///C:/dir on Windows engines
+	if len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return p, nil
+}
+
+// pathToURI encodes the path as a file URI.
+func pathToURI(p string) string {
+	p = filepath.ToSlash(p)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	u := url.URL{Scheme: "file", Path: p}
+	return u.String()
+}
+
+// trimDir returns the rest of p inside dir. Both are slash separated.
+func trimDir(p, dir string) (string, bool) {
+	dir = strings.TrimSuffix(dir, "/")
+	if p == dir {
+		return "", true
+	}
+	if strings.HasPrefix(p, dir+"/") {
+		return p[len(dir)+1:], true
+	}
+	return "", false
+}
+
+// mappings returns path mappings with remote and local parts as cleaned
+// absolute paths. Mappings of the launch configuration come first and
+// BasePath is mapped to the project root.
+func (xc *Client) mappings() []PathMapping {
+	var res []PathMapping
+	add := func(remote, local string) {
+		if r, err := uriToPath(remote); err == nil {
+			remote = r
+		}
+		if !filepath.IsAbs(local) {
+			local = filepath.Join(xc.root(), local)
+		}
+		res = append(res, PathMapping{
+			Remote: path.Clean(remote),
+			Local:  filepath.Clean(local),
+		})
+	}
+
+	if xc.launch != nil {
+		for _, m := range xc.launch.PathMappings {
+			add(m.Remote, m.Local)
+		}
+	}
+	for _, m := range xc.PathMappings {
+		add(m.Remote, m.Local)
+	}
+	if xc.BasePath != "" {
+		add(xc.BasePath, xc.root())
+	}
+	return res
+}
+
+// root returns the project root or the current directory.
+func (xc *Client) root() string {
+	if xc.Root != "" {
+		return xc.Root
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// LocalPath returns the local file of the engine file URI. Without path
+// mappings engine paths are local paths.
+func (xc *Client) LocalPath(uri string) (string, error) {
+	p, err := uriToPath(uri)
+	if err != nil {
+		return "", err
+	}
+
+	mappings := xc.mappings()
+	if len(mappings) == 0 {
+		return filepath.FromSlash(p), nil
+	}
+
+	for _, m := range mappings {
+		if rest, ok := trimDir(p, m.Remote); ok {
+			return filepath.Join(m.Local, filepath.FromSlash(rest)), nil
+		}
+	}
+
+	return "", fmt.Errorf("no path mapping for remote file %s", p)
+}
+
+// LocalFile returns the local file of the engine file URI if it exists.
+func (xc *Client) LocalFile(uri string) (string, error) {
+	fname, err := xc.LocalPath(uri)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(fname); err != nil {
+		return "", fmt.Errorf("%s: no local file", uri)
+	}
+	return fname, nil
+}
+
+// remoteURI returns the engine file URI of the local file.
+func (xc *Client) remoteURI(fname string) (string, error) {
+	if !filepath.IsAbs(fname) {
+		fname = filepath.Join(xc.root(), fname)
+	}
+	fname = filepath.Clean(fname)
+
+	mappings := xc.mappings()
+	if len(mappings) == 0 {
+		return pathToURI(fname), nil
+	}
+
+	for _, m := range mappings {
+		if rest, ok := trimDir(filepath.ToSlash(fname), filepath.ToSlash(m.Local)); ok {
+			return pathToURI(path.Join(m.Remote, rest)), nil
+		}
+	}
+
+	return "", fmt.Errorf("no path mapping for local file %s", fname)
+}
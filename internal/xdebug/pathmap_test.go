@@ -0,0 +1,52 @@
+package xdebug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathMappings(t *testing.T) {
+	xc := &Client{
+		Root: "/home/me/app",
+		Config: Config{PathMappings: []PathMapping{
+			{Remote: "/var/www/vendor", Local: "/home/me/shared/vendor"},
+			{Remote: "file:///var/www", Local: "."},
+		}},
+	}
+
+	p, err := xc.LocalPath("file:///var/www/src/My%20File.php")
+	assert.NoError(t, err)
+	assert.Equal(t, "/home/me/app/src/My File.php", p)
+
+	p, err = xc.LocalPath("file:///var/www/vendor/lib/a.php")
+	assert.NoError(t, err)
+	assert.Equal(t, "/home/me/shared/vendor/lib/a.php", p)
+
+	_, err = xc.LocalPath("file:///usr/share/php/b.php")
+	assert.Error(t, err)
+
+	// the prefix must match whole directories
+	_, err = xc.LocalPath("file:///var/www2/index.php")
+	assert.Error(t, err)
+
+	uri, err := xc.remoteURI("/home/me/app/src/My File.php")
+	assert.NoError(t, err)
+	assert.Equal(t, "file:///var/www/src/My%20File.php", uri)
+
+	uri, err = xc.remoteURI("/home/me/shared/vendor/lib/a.php")
+	assert.NoError(t, err)
+	assert.Equal(t, "file:///var/www/vendor/lib/a.php", uri)
+
+	_, err = xc.remoteURI("/tmp/x.php")
+	assert.Error(t, err)
+
+	// without mappings the engine runs locally
+	xc = &Client{}
+	p, err = xc.LocalPath("file:///tmp/a%23b.php")
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/a#b.php", p)
+	uri, err = xc.remoteURI("/tmp/a#b.php")
+	assert.NoError(t, err)
+	assert.Equal(t, "file:///tmp/a%23b.php", uri)
+}
@@ -0,0 +1,195 @@
+package xdebug
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Program is a scripted PHP program executed by the Simulator. Steps are
+// the executed lines in order, a function call is a step with a greater
+// depth than the step before it.
+type Program struct {
+	Files map[string]string `yaml:"files"` // sources by file URI, files without source are read from disk
+	Steps []Step            `yaml:"steps"`
+}
+
+// Step is an executed line of a Program. Empty File and Function are the
+// ones of the previous step of the same depth.
+type Step struct {
+	File     string                 `yaml:"file"`     // file URI, relative paths are relative to the program file
+	Line     int                    `yaml:"line"`     // 1-based line
+	Function string                 `yaml:"function"` // function executing the line, {main} in the main script
+	Depth    int                    `yaml:"depth"`    // call depth, 0 in the main script
+	Vars     map[string]interface{} `yaml:"vars"`     // locals assigned before the line, e.g. the arguments of a call
+	Output   string                 `yaml:"output"`   // written to stdout by the line
+}
+
+// ReadProgram reads the YAML program file.
+func ReadProgram(fname string) (*Program, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, fmt.Errorf("program read. error: %w", err)
+	}
+
+	var p Program
+	if err := yaml.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("program decode. error: %w", err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(fname))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string)
+	for name, src := range p.Files {
+		files[programURI(dir, name)] = src
+	}
+	p.Files = files
+	for i := range p.Steps {
+		if p.Steps[i].File != "" {
+			p.Steps[i].File = programURI(dir, p.Steps[i].File)
+		}
+	}
+
+	if err := p.normalize(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fname, err)
+	}
+	return &p, nil
+}
+
+// programURI returns the file URI of the file of a program in dir.
+func programURI(dir, name string) string {
+	if strings.Contains(name, "://") {
+		return name
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+	return pathToURI(name)
+}
+
+// normalize fills in the files and functions of the steps and checks
+// their depths.
+func (p *Program) normalize() error {
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("program without steps")
+	}
+
+	var files, functions []string
+	for i := range p.Steps {
+		st := &p.Steps[i]
+		if st.Depth < 0 || st.Depth > len(files) {
+			return fmt.Errorf("step %d: depth %d, expected 0 to %d", i+1, st.Depth, len(files))
+		}
+		if st.Depth == len(files) {
+			// a call
+			files = append(files, "")
+			functions = append(functions, "")
+		}
+		files, functions = files[:st.Depth+1], functions[:st.Depth+1]
+
+		if st.File == "" {
+			st.File = files[st.Depth]
+		}
+		if st.File == "" && st.Depth > 0 {
+			st.File = files[st.Depth-1]
+		}
+		if st.File == "" {
+			return fmt.Errorf("step %d: file expected", i+1)
+		}
+		if st.Function == "" {
+			st.Function = functions[st.Depth]
+		}
+		if st.Function == "" && st.Depth == 0 {
+			st.Function = "{main}"
+		}
+		if st.Function == "" {
+			return fmt.Errorf("step %d: function expected", i+1)
+		}
+		files[st.Depth], functions[st.Depth] = st.File, st.Function
+	}
+	return nil
+}
+
+// source returns the source of the file URI.
+func (p *Program) source(uri string) (string, error) {
+	if src, ok := p.Files[uri]; ok {
+		return src, nil
+	}
+	fname, err := uriToPath(uri)
+	if err != nil {
+		return "", err
+	}
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// demoFiles are the sources of the demo program.
+var demoFiles = map[string]string{
+	"index.php": `<?php
+require 'greet.php';
+
+$names = ['Ada', 'Grace', 'Linus'];
+$count = 0;
+foreach ($names as $name) {
+    echo greet($name), "\n";
+    $count++;
+}
+echo "greeted $count people\n";
+`,
+	"greet.php": `<?php
+
+function greet(string $name): string
+{
+    $greeting = 'Hello, ' . $name;
+    return $greeting . '!';
+}
+`,
+}
+
+// DemoProgram writes the sources of the demo program into dir and returns
+// the program executing them.
+func DemoProgram(dir string) (*Program, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("demo dir. error: %w", err)
+	}
+	for name, src := range demoFiles {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			return nil, fmt.Errorf("demo file. error: %w", err)
+		}
+	}
+
+	index := pathToURI(filepath.Join(dir, "index.php"))
+	greet := pathToURI(filepath.Join(dir, "greet.php"))
+	p := &Program{Steps: []Step{
+		{File: index, Line: 2},
+		{Line: 4},
+		{Line: 5, Vars: map[string]interface{}{"$names": []interface{}{"Ada", "Grace", "Linus"}}},
+		{Line: 6, Vars: map[string]interface{}{"$count": 0}},
+	}}
+	for i, name := range []string{"Ada", "Grace", "Linus"} {
+		greeting := "Hello, " + name
+		p.Steps = append(p.Steps,
+			Step{Line: 7, Vars: map[string]interface{}{"$name": name}},
+			Step{File: greet, Function: "greet", Depth: 1, Line: 5, Vars: map[string]interface{}{"$name": name}},
+			Step{Depth: 1, Line: 6, Vars: map[string]interface{}{"$greeting": greeting}},
+			Step{Line: 7, Output: greeting + "!\n"},
+			Step{Line: 8},
+			Step{Line: 6, Vars: map[string]interface{}{"$count": i + 1}},
+		)
+	}
+	p.Steps = append(p.Steps, Step{Line: 10, Output: "greeted 3 people\n"})
+
+	if err := p.normalize(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -7,87 +7,176 @@ import (
 	"io"
 	"log"
 	"net"
+	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"golang.org/x/net/html/charset"
 )
 
-func listen() (*net.TCPListener, error) {
-	var err error
+// DefaultPort is the DBGp port used by Xdebug 3.
+const DefaultPort = 9003
 
-	tcpAddr, err := net.ResolveTCPAddr("tcp", "172.17.0.1:9003")
-	if err != nil {
-		return nil, fmt.Errorf("resolve. error: %w", err)
+// listenAddr returns the network and address of the listener. Addresses
+// starting with "unix:" are Unix domain socket paths and ignore the port.
+func listenAddr(address string, port int) (string, string) {
+	if strings.HasPrefix(address, "unix:") {
+		return "unix", strings.TrimPrefix(address, "unix:")
+	}
+	if address == "" {
+		address = "127.0.0.1"
 	}
+	if port == 0 {
+		port = DefaultPort
+	}
+	return "tcp", net.JoinHostPort(address, strconv.Itoa(port))
+}
 
-	l, err := net.ListenTCP("tcp", tcpAddr)
+func listen(network, addr string) (net.Listener, error) {
+	if network == "unix" {
+		// the socket may be left over by a crashed session, other files
+		// are not removed
+		fi, err := os.Lstat(addr)
+		if err == nil && fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("listen: %s exists and is not a socket", addr)
+		}
+		if err == nil {
+			err = os.Remove(addr)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("remove socket. error: %w", err)
+		}
+	}
+
+	l, err := net.Listen(network, addr)
 	if err != nil {
 		return nil, fmt.Errorf("listen. error: %w", err)
-
 	}
 	return l, nil
 }
 
-func accept(l *net.TCPListener) (*net.TCPConn, error) {
-	var err error
+// initTimeout limits waiting for the init packet of an accepted connection,
+// so that a client sending nothing does not block accepting the engine.
+var initTimeout = 5 * time.Second
+
+// accept waits for an engine connection and reads its init packet.
+// Connections with an idekey other than idekey, if set, are closed. A zero
+// timeout waits until the listener is closed.
+func accept(l net.Listener, timeout time.Duration, idekey string) (net.Conn, Response, error) {
+	log.Println("waiting for connect from xdebug to", l.Addr())
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+		d, ok := l.(interface{ SetDeadline(time.Time) error })
+		if ok {
+			if err := d.SetDeadline(deadline); err != nil {
+				return nil, Response{}, fmt.Errorf("set accept deadline. error: %w", err)
+			}
+		}
+	}
 
-	log.Println("waiting for connect from xdebug to :9003")
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return nil, Response{}, fmt.Errorf("no debugger connection in %v", timeout)
+			}
+			return nil, Response{}, fmt.Errorf("accept. error: %w", err)
+		}
 
-	if err := l.SetDeadline(time.Now().Add(time.Second * 5)); err != nil {
-		return nil, fmt.Errorf("set accept deadline. error: %w", err)
-	}
+		readDeadline := time.Now().Add(initTimeout)
+		if !deadline.IsZero() && deadline.Before(readDeadline) {
+			readDeadline = deadline
+		}
+		if err := conn.SetReadDeadline(readDeadline); err != nil {
+			log.Println("set init deadline. error:", err)
+			conn.Close()
+			continue
+		}
+		init, err := readInit(conn)
+		if err == nil {
+			err = conn.SetReadDeadline(time.Time{})
+		}
+		if err != nil {
+			log.Println(err)
+			conn.Close()
+			continue
+		}
 
-	conn, err := l.AcceptTCP()
-	if err != nil {
-		return nil, fmt.Errorf("accept. error: %w", err)
+		if idekey != "" && init.IDEKey != idekey {
+			log.Printf("rejected connection with idekey %q", init.IDEKey)
+			conn.Close()
+			continue
+		}
+
+		log.Println("accepted", init.IDEKey, init.FileURI)
+
+		return conn, init, nil
 	}
+}
 
+func readInit(conn net.Conn) (Response, error) {
 	b, err := readBlock(conn)
 	if err != nil {
-		return nil, fmt.Errorf("read block. error: %w", err)
+		return Response{}, fmt.Errorf("read init packet. error: %w", err)
 	}
-
-	if _, err := unmarshalCommand(b); err != nil {
-		return nil, err
+	init, err := unmarshalCommand(b)
+	if err != nil {
+		return Response{}, err
 	}
-
-	log.Println("accepted")
-
-	return conn, nil
+	if init.XMLName.Local != "init" {
+		return Response{}, fmt.Errorf("init packet expected, got %s", init.XMLName.Local)
+	}
+	init.packet = bytes.TrimRight(b, "\x00")
+	return init, nil
 }
 
-func initProxy(addr, s string) {
-	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
-	if err != nil {
-		log.Fatal(err)
-	}
+// proxyTimeout limits connecting to the DBGp proxy and waiting for its reply.
+var proxyTimeout = 5 * time.Second
+
+// proxyCommand sends the proxyinit or proxystop command to the DBGp proxy at
+// addr and returns its reply.
+func proxyCommand(addr, cmd string) (proxyResponse, error) {
+	var resp proxyResponse
 
-	conn, err := net.DialTCP("tcp", nil, tcpAddr)
+	conn, err := net.DialTimeout("tcp", addr, proxyTimeout)
 	if err != nil {
-		log.Fatal(err)
+		return resp, fmt.Errorf("proxy connect. error: %w", err)
 	}
 	defer conn.Close()
 
-	n, err := conn.Write([]byte(s))
-	if err != nil {
-		log.Fatal(err)
+	if err := conn.SetDeadline(time.Now().Add(proxyTimeout)); err != nil {
+		return resp, fmt.Errorf("proxy set deadline. error: %w", err)
 	}
 
-	log.Println("write to server = ", s, n)
+	log.Println("proxy send:", cmd)
+	if _, err := conn.Write([]byte(cmd + "\x00")); err != nil {
+		return resp, fmt.Errorf("proxy write. error: %w", err)
+	}
 
-	buf, err := readBlock(conn)
+	b, err := readBlock(conn)
 	if err != nil {
-		log.Fatal(err)
+		return resp, fmt.Errorf("proxy read. error: %w", err)
 	}
+	log.Println("proxy reply:", string(b))
 
-	log.Println("reply from server=", string(buf))
-	//	fmt.Println(addr, s, string(buf))
+	dec := xml.NewDecoder(bytes.NewReader(bytes.TrimRight(b, "\x00")))
+	dec.CharsetReader = charset.NewReaderLabel
+	if err := dec.Decode(&resp); err != nil {
+		return resp, fmt.Errorf("proxy reply. error: %w", err)
+	}
 
-	var resp proxyResponse
-	err = xml.Unmarshal(buf, &resp)
-	if err != nil {
-		log.Fatal(err)
+	if resp.Success != 1 {
+		msg := resp.Error.Message
+		if msg == "" {
+			msg = "request failed"
+		}
+		return resp, fmt.Errorf("%s: proxy error %d: %s", resp.XMLName.Local, resp.Error.ID, msg)
 	}
 
-	fmt.Printf("%v, resp: %+v\n", err, resp)
+	return resp, nil
 }
 
 func readBlock(r io.Reader) ([]byte, error) {
@@ -102,14 +191,13 @@ func readBlock(r io.Reader) ([]byte, error) {
 		if c[0] == 0 {
 			break
 		}
-		if c[0] < '0' && c[0] > 9 {
-			log.Fatal("not a number")
+		if c[0] < '0' || c[0] > '9' {
+			return nil, fmt.Errorf("packet length: unexpected byte %q", c[0])
 		}
 
 		length = length*10 + int64(c[0]-'0')
 	}
 
-	log.Println("length", length)
 	var b bytes.Buffer
 	n, err := io.CopyN(&b, r, length+1)
 	if err != nil {
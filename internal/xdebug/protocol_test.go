@@ -0,0 +1,115 @@
+package xdebug
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenAddr(t *testing.T) {
+	network, addr := listenAddr("", 0)
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, "127.0.0.1:9003", addr)
+
+	network, addr = listenAddr("::1", 9000)
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, "[::1]:9000", addr)
+
+	network, addr = listenAddr("unix:/tmp/dbgp.sock", 9000)
+	assert.Equal(t, "unix", network)
+	assert.Equal(t, "/tmp/dbgp.sock", addr)
+}
+
+func TestAcceptIDEKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xdebug")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sock := filepath.Join(dir, "dbgp.sock")
+	l, err := listen("unix", sock)
+	assert.NoError(t, err)
+	defer l.Close()
+
+	connect := func(idekey string) net.Conn {
+		conn, err := net.Dial("unix", sock)
+		assert.NoError(t, err)
+		writePacket(t, conn, `<init idekey="`+idekey+`" fileuri="file:///var/www/index.php"/>`)
+		return conn
+	}
+
+	other := connect("other")
+	defer other.Close()
+	mine := connect("mine")
+	defer mine.Close()
+
+	conn, init, err := accept(l, time.Second, "mine")
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "mine", init.IDEKey)
+	assert.Equal(t, "file:///var/www/index.php", init.FileURI)
+
+	// the rejected connection is closed
+	_, err = other.Read(make([]byte, 1))
+	assert.Error(t, err)
+
+	_, _, err = accept(l, 10*time.Millisecond, "")
+	assert.EqualError(t, err, "no debugger connection in 10ms")
+}
+
+func TestAcceptSilentClient(t *testing.T) {
+	defer func(d time.Duration) { initTimeout = d }(initTimeout)
+	initTimeout = 50 * time.Millisecond
+
+	l, err := listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	// a client sending nothing does not block the engine connecting next
+	silent, err := net.Dial("tcp", l.Addr().String())
+	assert.NoError(t, err)
+	defer silent.Close()
+	engine, err := net.Dial("tcp", l.Addr().String())
+	assert.NoError(t, err)
+	defer engine.Close()
+	writePacket(t, engine, `<init idekey="x" fileuri="file:///var/www/index.php"/>`)
+
+	conn, init, err := accept(l, time.Second, "")
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "x", init.IDEKey)
+
+	// the deadline is cleared for the session
+	time.Sleep(2 * initTimeout)
+	writePacket(t, engine, `<response command="run" transaction_id="1"/>`)
+	_, err = readBlock(conn)
+	assert.NoError(t, err)
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xdebug")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// files other than sockets are kept
+	fname := filepath.Join(dir, "notes.txt")
+	assert.NoError(t, ioutil.WriteFile(fname, []byte("keep"), 0644))
+	_, err = listen("unix", fname)
+	assert.EqualError(t, err, "listen: "+fname+" exists and is not a socket")
+	_, err = os.Stat(fname)
+	assert.NoError(t, err)
+
+	// a socket left over is replaced
+	sock := filepath.Join(dir, "dbgp.sock")
+	l, err := net.Listen("unix", sock)
+	assert.NoError(t, err)
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	l, err = listen("unix", sock)
+	assert.NoError(t, err)
+	l.Close()
+}
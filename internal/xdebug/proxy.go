@@ -0,0 +1,107 @@
+package xdebug
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/shell"
+)
+
+// ProxyInit registers the IDE key and the listener port with the DBGp proxy
+// Proxy. The proxy then forwards engine connections with the IDE key to the
+// listener. The request runs in the background and its reply is reported to
+// the editor. An open listener keeps the configuration it was started with,
+// otherwise the project debug file is read like on start.
+func (xc *Client) ProxyInit() error {
+	if xc.listener == nil {
+		if err := xc.readConfig(); err != nil {
+			return err
+		}
+	}
+
+	proxy, idekey, err := xc.proxyParams()
+	if err != nil {
+		return err
+	}
+
+	network, addr := listenAddr(xc.Address, xc.Port)
+	if network != "tcp" {
+		return fmt.Errorf("proxyinit: the proxy cannot connect to a %s listener", network)
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	cmd := fmt.Sprintf("proxyinit -p %s -k %s -m 1", port, idekey)
+	go func() {
+		resp, err := proxyCommand(proxy, cmd)
+		shell.Post(func() {
+			if err != nil {
+				log.Println(err)
+				xc.Editor.Error(err)
+				return
+			}
+			xc.proxy, xc.proxyKey = proxy, idekey
+			xc.Editor.Message(fmt.Sprintf("registered %s with DBGp proxy %s, engines connect to %s",
+				resp.Idekey, proxy, net.JoinHostPort(resp.Address, strconv.Itoa(resp.Port))))
+		})
+	}()
+
+	return nil
+}
+
+// ProxyStop unregisters the IDE key from the DBGp proxy in the background.
+func (xc *Client) ProxyStop() error {
+	if xc.proxy == "" {
+		return fmt.Errorf("not registered with a DBGp proxy")
+	}
+
+	proxy, idekey := xc.proxy, xc.proxyKey
+	go func() {
+		_, err := proxyCommand(proxy, "proxystop -k "+idekey)
+		shell.Post(func() {
+			if err != nil {
+				log.Println(err)
+				xc.Editor.Error(err)
+				return
+			}
+			if xc.proxy == proxy && xc.proxyKey == idekey {
+				xc.proxy, xc.proxyKey = "", ""
+			}
+			xc.Editor.Message(fmt.Sprintf("unregistered %s from DBGp proxy %s", idekey, proxy))
+		})
+	}()
+
+	return nil
+}
+
+// Shutdown unregisters from the DBGp proxy and closes the client. It waits
+// for the proxy reply and is meant to be called when the editor quits.
+func (xc *Client) Shutdown() {
+	if xc.proxy != "" {
+		if _, err := proxyCommand(xc.proxy, "proxystop -k "+xc.proxyKey); err != nil {
+			log.Println(err)
+		}
+		xc.proxy, xc.proxyKey = "", ""
+	}
+	if err := xc.Close(); err != nil {
+		log.Println(err)
+	}
+}
+
+func (xc *Client) proxyParams() (string, string, error) {
+	if xc.Proxy == "" {
+		return "", "", fmt.Errorf("no DBGp proxy configured")
+	}
+	if xc.IDEKey == "" {
+		return "", "", fmt.Errorf("an idekey is required to register with the DBGp proxy")
+	}
+	if strings.ContainsAny(xc.IDEKey, " \t\"") {
+		return "", "", fmt.Errorf("invalid idekey %q", xc.IDEKey)
+	}
+	return xc.Proxy, xc.IDEKey, nil
+}
@@ -0,0 +1,90 @@
+package xdebug
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// fakeProxy answers each command read from the IDE port with the reply.
+func fakeProxy(t *testing.T, replies map[string]string) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			cmd := readCommand(t, conn)
+			writePacket(t, conn, `<?xml version="1.0" encoding="iso-8859-1"?>`+"\n"+replies[cmd])
+			conn.Close()
+		}
+	}()
+
+	return l.Addr().String(), func() { l.Close() }
+}
+
+func TestProxy(t *testing.T) {
+	addr, stop := fakeProxy(t, map[string]string{
+		"proxyinit -p 9000 -k mykey -m 1": `<proxyinit success="1" idekey="mykey" address="10.0.0.1" port="9001"/>`,
+		"proxystop -k mykey":              `<proxystop success="1" idekey="mykey"/>`,
+		"proxyinit -p 9000 -k taken -m 1": `<proxyinit success="0"><error id="3"><message>IDE Key already exists</message></error></proxyinit>`,
+	})
+	defer stop()
+
+	dir, err := ioutil.TempDir("", "proxy")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ed := &testEditor{}
+	xc := &Client{Options: Config{Proxy: addr, IDEKey: "mykey", Port: 9000}, Root: dir, Editor: ed}
+
+	assert.NoError(t, xc.ProxyInit())
+	runJob(t)
+	assert.Equal(t, "registered mykey with DBGp proxy "+addr+", engines connect to 10.0.0.1:9001", ed.messages[0])
+
+	assert.NoError(t, xc.ProxyStop())
+	runJob(t)
+	assert.Equal(t, "unregistered mykey from DBGp proxy "+addr, ed.messages[1])
+	assert.Error(t, xc.ProxyStop())
+
+	xc.Options.IDEKey = "taken"
+	assert.NoError(t, xc.ProxyInit())
+	runJob(t)
+	assert.Equal(t, []string{"proxyinit: proxy error 3: IDE Key already exists"}, ed.errors)
+
+	xc.Options.IDEKey = ""
+	assert.Error(t, xc.ProxyInit())
+	xc.Options.IDEKey = "mykey"
+	xc.Options.Address = "unix:/tmp/dbgp.sock"
+	assert.Error(t, xc.ProxyInit())
+}
+
+func TestProxyProjectConfig(t *testing.T) {
+	addr, stop := fakeProxy(t, map[string]string{
+		"proxyinit -p 9100 -k projkey -m 1": `<proxyinit success="1" idekey="projkey" address="10.0.0.1" port="9001"/>`,
+	})
+	defer stop()
+
+	dir, err := ioutil.TempDir("", "proxy")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, debugger.ProjectDir), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, debugger.ProjectDir, "debug.yaml"),
+		[]byte("idekey: projkey\nport: 9100\nproxy: "+addr+"\n"), 0644))
+
+	// the keys of the debug file override the options
+	ed := &testEditor{}
+	xc := &Client{Options: Config{Proxy: "127.0.0.1:1", IDEKey: "mykey", Port: 9000}, Root: dir, Editor: ed}
+	assert.NoError(t, xc.ProxyInit())
+	runJob(t)
+	assert.Empty(t, ed.errors)
+	assert.Equal(t, "registered projkey with DBGp proxy "+addr+", engines connect to 10.0.0.1:9001", ed.messages[0])
+}
@@ -0,0 +1,273 @@
+package xdebug
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"path"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// session is the connection of one engine, e.g. of one HTTP request.
+// Breakpoints and watches are shared by all sessions of the client.
+type session struct {
+	xc *Client
+
+	id      int    // session number shown in the session list
+	idekey  string // idekey from the init packet
+	fileuri string // script from the init packet
+
+	conn     *transport // accepted xdebugger connection
+	status   string     // engine status from the last response
+	currLine int
+	currFile string
+	source   []string // lines of currFile
+
+	where    string                       // function of the innermost stack frame
+	executed []int                        // lines of currFile executed in the function, oldest first
+	inline   map[int][]*debugger.Property // values of the variables assigned on executed lines
+
+	features map[string]string // engine features read with feature_get
+
+	runTo       int  // engine ID of the temporary breakpoint of RunTo
+	filterSteps bool // step out of files matching the step filters
+
+	engineBreakpoints map[string]map[int]int       // local file -> line -> engine breakpoint ID
+	breakpointIDs     map[*debugger.Breakpoint]int // engine IDs of the command line breakpoints
+	resolvedLines     map[int]int                  // engine breakpoint ID -> line resolved by the engine
+
+	contexts []*debugger.Context // variable contexts of the current stack frame
+	depth    int                 // stack depth of the current frame
+	stack    []debugger.Frame
+}
+
+func (s *session) isCurrent() bool {
+	return s.xc.current == s
+}
+
+// Sessions returns the active sessions in the order they were connected.
+func (xc *Client) Sessions() []debugger.SessionInfo {
+	var res []debugger.SessionInfo
+	for _, s := range xc.sessions {
+		res = append(res, debugger.SessionInfo{
+			ID:       s.id,
+			IDEKey:   s.idekey,
+			File:     s.fileuri,
+			Language: s.language(),
+			Status:   s.status,
+			Current:  s.isCurrent(),
+		})
+	}
+	return res
+}
+
+func (xc *Client) session(id int) (*session, error) {
+	for _, s := range xc.sessions {
+		if s.id == id {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("no debug session %d", id)
+}
+
+// SelectSession makes the session current. Step and eval commands are sent
+// to the current session.
+func (xc *Client) SelectSession(id int) error {
+	s, err := xc.session(id)
+	if err != nil {
+		return err
+	}
+	xc.selectSession(s)
+	return nil
+}
+
+func (xc *Client) selectSession(s *session) {
+	xc.current = s
+	if s.status == "break" {
+		s.jumpTo(s.currFile, s.currLine)
+		s.refresh()
+	}
+	xc.Editor.VariablesChanged()
+	xc.Editor.StackChanged()
+	xc.Editor.SessionsChanged()
+	xc.Editor.LocationChanged()
+	xc.Editor.Message(fmt.Sprintf("session %d: %s", s.id, s.status))
+}
+
+// DetachSession detaches the engine of the session, the script continues
+// without the debugger.
+func (xc *Client) DetachSession(id int) error {
+	s, err := xc.session(id)
+	if err != nil {
+		return err
+	}
+	return s.command("detach", "", nil, nil)
+}
+
+// StopSession closes the session, the engine ends the script.
+func (xc *Client) StopSession(id int) error {
+	s, err := xc.session(id)
+	if err != nil {
+		return err
+	}
+	xc.endSession(s)
+	return nil
+}
+
+// connected starts a session on the accepted engine connection. The first
+// session becomes current.
+func (xc *Client) connected(conn net.Conn, init Response) {
+	if xc.SessionIDs == nil {
+		xc.SessionIDs = &debugger.SessionCounter{}
+	}
+	s := &session{
+		xc:      xc,
+		id:      xc.SessionIDs.Next(),
+		idekey:  init.IDEKey,
+		fileuri: init.FileURI,
+	}
+	s.conn = newTransport(conn)
+	s.conn.onClose = s.onClose
+	s.conn.onStream = s.onStream
+	s.conn.onNotify = s.onNotify
+	xc.record(s, init)
+	s.conn.start()
+
+	xc.sessions = append(xc.sessions, s)
+	if xc.current == nil {
+		xc.current = s
+	}
+	xc.Editor.SessionsChanged()
+
+	s.negotiateFeatures()
+	s.redirectOutput()
+
+	if err := s.processParameters(); err != nil {
+		log.Println(err)
+		xc.Editor.Error(err)
+	}
+
+	first := "run"
+	if xc.breakFirst() {
+		first = "step_into"
+	}
+	if err := s.step(first); err != nil {
+		log.Println(err)
+		xc.Editor.Error(err)
+		return
+	}
+	if first == "run" {
+		s.status = "running"
+	}
+
+	if s.isCurrent() {
+		xc.Editor.Message("started. F5-run F6-step_out F7-step_in F8-step_over F9-break F10-exit")
+	} else {
+		xc.Editor.Message(fmt.Sprintf("session %d started: %s", s.id, path.Base(s.fileuri)))
+	}
+}
+
+// endSession closes the session. If it was current the last connected
+// session becomes current. The debugger stops with the last session unless
+// it is in listen mode.
+func (xc *Client) endSession(s *session) {
+	s.close()
+
+	for i, o := range xc.sessions {
+		if o == s {
+			xc.sessions = append(xc.sessions[:i], xc.sessions[i+1:]...)
+			break
+		}
+	}
+	xc.Editor.SessionsChanged()
+
+	if !s.isCurrent() {
+		xc.Editor.Message(fmt.Sprintf("session %d ended", s.id))
+		return
+	}
+
+	xc.current = nil
+	xc.Editor.LocationChanged()
+	if len(xc.sessions) > 0 {
+		xc.selectSession(xc.sessions[len(xc.sessions)-1])
+		return
+	}
+
+	xc.stop()
+}
+
+// redirectOutput asks the engine to copy the script output to the IDE.
+// Engines not supporting the redirection only log an error.
+func (s *session) redirectOutput() {
+	for _, stream := range []string{"stdout", "stderr"} {
+		stream := stream
+		_, err := s.conn.send(stream, "-c 1", nil, func(resp Response) {
+			if resp.Error.Code != 0 {
+				log.Printf("%s redirection: error %d: %s", stream, resp.Error.Code, resp.Error.Message.Text)
+			}
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// onStream passes the output copied by the engine to the editor.
+func (s *session) onStream(resp Response) {
+	text := resp.Text
+	if resp.Encoding == "base64" {
+		b, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			log.Println("stream:", err)
+			return
+		}
+		text = string(b)
+	}
+	s.xc.Editor.Output(s.id, text)
+}
+
+// onNotify handles the notifications of the engine.
+func (s *session) onNotify(resp Response) {
+	switch resp.Name {
+	case "breakpoint_resolved":
+		for _, b := range resp.Breakpoints {
+			s.resolveBreakpoint(b)
+		}
+	}
+}
+
+// close closes the engine connection and resets the session state.
+func (s *session) close() {
+	if s.conn != nil {
+		if err := s.conn.close(); err != nil {
+			log.Println(err)
+		}
+		s.conn = nil
+	}
+	s.status = ""
+	s.contexts = nil
+	s.stack = nil
+	s.depth = 0
+	s.source = nil
+	s.where = ""
+	s.executed = nil
+	s.inline = nil
+	s.runTo = 0
+	s.filterSteps = false
+	s.engineBreakpoints = nil
+	s.breakpointIDs = nil
+	s.resolvedLines = nil
+}
+
+// onClose is called when the engine closes the connection.
+func (s *session) onClose(err error) {
+	if s.conn == nil {
+		return
+	}
+	s.xc.endSession(s)
+	if err != nil {
+		s.xc.Editor.Error(fmt.Sprintf("session %d: debugger connection closed: %v", s.id, err))
+	}
+}
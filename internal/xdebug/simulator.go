@@ -0,0 +1,849 @@
+package xdebug
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// simulatorKey is the idekey of the simulator init packet.
+const simulatorKey = "micro-demo"
+
+// demoTimeout limits waiting for the simulator connection.
+const demoTimeout = 5 * time.Second
+
+// Simulator is a fake DBGp engine executing a scripted Program. It connects
+// to the client the way Xdebug does, so the debugger can be driven without
+// PHP. Commands it does not know are answered with error 4.
+type Simulator struct {
+	Program *Program
+
+	steps       []Step
+	pos         int    // current step, -1 before the first step
+	status      string // starting, break or stopping
+	stdout      bool   // output is copied to the client
+	breakpoints []*simBreakpoint
+	lastID      int
+
+	w io.Writer
+}
+
+// simBreakpoint is a breakpoint set with breakpoint_set.
+type simBreakpoint struct {
+	id           int
+	typ          string
+	file         string
+	line         int
+	function     string
+	exception    string
+	expression   string
+	hitValue     int
+	hitCondition string
+	hitCount     int
+}
+
+// NewSimulator returns a simulator executing the program.
+func NewSimulator(p *Program) *Simulator {
+	return &Simulator{Program: p}
+}
+
+// Dial connects to the client listening on addr and serves it until the
+// connection is closed.
+func (sim *Simulator) Dial(network, addr string) error {
+	conn, err := net.DialTimeout(network, addr, demoTimeout)
+	if err != nil {
+		return fmt.Errorf("simulator connect. error: %w", err)
+	}
+	defer conn.Close()
+	return sim.Serve(conn)
+}
+
+// Serve runs the program from the start: it sends the init packet on rw and
+// answers the commands until the connection is closed or the session is
+// stopped.
+func (sim *Simulator) Serve(rw io.ReadWriter) error {
+	if len(sim.Program.Steps) == 0 {
+		return fmt.Errorf("simulator: program without steps")
+	}
+	// variables set by the client change the copy only
+	sim.steps = append([]Step(nil), sim.Program.Steps...)
+	sim.pos = -1
+	sim.status = "starting"
+	sim.stdout = false
+	sim.breakpoints = nil
+	sim.w = rw
+
+	init := fmt.Sprintf(`<init xmlns="urn:debugger_protocol_v1" fileuri="%s" language="PHP" protocol_version="1.0" appid="%d" idekey="%s"><engine version="1.0"><![CDATA[micro DBGp simulator]]></engine></init>`,
+		xmlAttr(sim.steps[0].File), len(sim.steps), simulatorKey)
+	if err := sim.write(init); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(rw)
+	for {
+		s, err := r.ReadString(0)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("simulator read. error: %w", err)
+		}
+		cmd, err := parseSimCommand(strings.TrimSuffix(s, "\x00"))
+		if err != nil {
+			log.Println("simulator:", err)
+			continue
+		}
+
+		if err := sim.write(sim.handle(cmd)); err != nil {
+			return err
+		}
+		if cmd.name == "stop" || cmd.name == "detach" {
+			return nil
+		}
+	}
+}
+
+func (sim *Simulator) write(packet string) error {
+	packet = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + packet
+	if _, err := fmt.Fprintf(sim.w, "%d\x00%s\x00", len(packet), packet); err != nil {
+		return fmt.Errorf("simulator write. error: %w", err)
+	}
+	return nil
+}
+
+// simCommand is a DBGp command received by the simulator.
+type simCommand struct {
+	name string
+	id   string
+	args map[string]string
+	data string
+}
+
+// parseSimCommand parses the command like "property_get -i 5 -n "$a b" -- ZGF0YQ==".
+func parseSimCommand(s string) (simCommand, error) {
+	cmd := simCommand{args: make(map[string]string)}
+	words, data := splitSimArgs(s)
+	if len(words) == 0 {
+		return cmd, fmt.Errorf("empty command")
+	}
+	cmd.name = words[0]
+	for i := 1; i < len(words); i++ {
+		if !strings.HasPrefix(words[i], "-") || i+1 == len(words) {
+			return cmd, fmt.Errorf("%s: unexpected %q", cmd.name, words[i])
+		}
+		cmd.args[words[i]] = words[i+1]
+		i++
+	}
+	cmd.id = cmd.args["-i"]
+	if data != "" {
+		b, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return cmd, fmt.Errorf("%s: data. error: %w", cmd.name, err)
+		}
+		cmd.data = string(b)
+	}
+	return cmd, nil
+}
+
+// splitSimArgs splits the command into words, double quoted words can
+// contain spaces and backslash escapes. The base64 data after -- is returned
+// separately.
+func splitSimArgs(s string) ([]string, string) {
+	var words []string
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return words, ""
+		}
+		if strings.HasPrefix(s, "-- ") {
+			return words, strings.TrimSpace(s[3:])
+		}
+		if s[0] != '"' {
+			i := strings.IndexByte(s, ' ')
+			if i < 0 {
+				i = len(s)
+			}
+			words = append(words, s[:i])
+			s = s[i:]
+			continue
+		}
+		var w strings.Builder
+		i := 1
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+			}
+			w.WriteByte(s[i])
+		}
+		words = append(words, w.String())
+		if i < len(s) {
+			i++
+		}
+		s = s[i:]
+	}
+}
+
+// handle executes the command and returns the response packet.
+func (sim *Simulator) handle(cmd simCommand) string {
+	switch cmd.name {
+	case "feature_get":
+		return sim.featureGet(cmd)
+	case "feature_set", "stdout", "stderr":
+		if cmd.name == "stdout" {
+			sim.stdout = cmd.args["-c"] != "0"
+		}
+		return sim.response(cmd, `success="1"`, "")
+	case "status":
+		return sim.response(cmd, sim.statusAttrs(), "")
+	case "step_into", "step_over", "step_out", "run", "break":
+		return sim.step(cmd)
+	case "detach", "stop":
+		status := "stopping"
+		if cmd.name == "stop" {
+			status = "stopped"
+		}
+		return sim.response(cmd, fmt.Sprintf(`status="%s" reason="ok"`, status), "")
+	case "breakpoint_set":
+		return sim.breakpointSet(cmd)
+	case "breakpoint_remove":
+		id, _ := strconv.Atoi(cmd.args["-d"])
+		for i, bp := range sim.breakpoints {
+			if bp.id == id {
+				sim.breakpoints = append(sim.breakpoints[:i], sim.breakpoints[i+1:]...)
+				return sim.response(cmd, "", "")
+			}
+		}
+		return sim.error(cmd, 205, "no such breakpoint")
+	case "breakpoint_list":
+		var b strings.Builder
+		for _, bp := range sim.breakpoints {
+			fmt.Fprintf(&b, `<breakpoint id="%d" type="%s" state="enabled" filename="%s" lineno="%d" function="%s" exception="%s" hit_count="%d" hit_value="%d" hit_condition="%s"/>`,
+				bp.id, bp.typ, xmlAttr(bp.file), bp.line, xmlAttr(bp.function), xmlAttr(bp.exception), bp.hitCount, bp.hitValue, xmlAttr(bp.hitCondition))
+		}
+		return sim.response(cmd, "", b.String())
+	}
+
+	if sim.status != "break" {
+		return sim.error(cmd, 5, "command is not available")
+	}
+
+	switch cmd.name {
+	case "stack_get":
+		var b strings.Builder
+		for level := 0; level <= sim.steps[sim.pos].Depth; level++ {
+			st := sim.steps[sim.frameStep(level)]
+			fmt.Fprintf(&b, `<stack where="%s" level="%d" type="file" filename="%s" lineno="%d"/>`,
+				xmlAttr(st.Function), level, xmlAttr(st.File), st.Line)
+		}
+		return sim.response(cmd, "", b.String())
+	case "source":
+		uri := cmd.args["-f"]
+		if uri == "" {
+			uri = sim.steps[sim.pos].File
+		}
+		src, err := sim.Program.source(uri)
+		if err != nil {
+			return sim.error(cmd, 100, err.Error())
+		}
+		return sim.response(cmd, `encoding="base64"`, cdata(base64.StdEncoding.EncodeToString([]byte(src))))
+	case "context_names":
+		return sim.response(cmd, "", `<context name="Locals" id="0"/>`)
+	case "context_get":
+		level, ok := sim.level(cmd)
+		if !ok {
+			return sim.error(cmd, 301, "stack depth invalid")
+		}
+		var b strings.Builder
+		if cmd.args["-c"] == "" || cmd.args["-c"] == "0" {
+			vars := sim.locals(level)
+			for _, name := range sortedKeys(vars) {
+				writeSimProperty(&b, name, name, vars[name], 1)
+			}
+		}
+		return sim.response(cmd, `context="0"`, b.String())
+	case "property_get", "property_value":
+		level, ok := sim.level(cmd)
+		if !ok {
+			return sim.error(cmd, 301, "stack depth invalid")
+		}
+		v, err := lookupSimValue(sim.locals(level), cmd.args["-n"])
+		if err != nil {
+			return sim.error(cmd, 300, err.Error())
+		}
+		var b strings.Builder
+		depth := 1
+		if cmd.args["-p"] != "" && cmd.args["-p"] != "0" {
+			// all children are on the first page
+			depth = 0
+		}
+		writeSimProperty(&b, cmd.args["-n"], cmd.args["-n"], v, depth)
+		return sim.response(cmd, "", b.String())
+	case "property_set":
+		return sim.propertySet(cmd)
+	case "eval":
+		v, err := evalSimExpr(sim.locals(0), cmd.data)
+		if err != nil {
+			return sim.error(cmd, 206, err.Error())
+		}
+		var b strings.Builder
+		writeSimProperty(&b, "", "", v, 1)
+		return sim.response(cmd, "", b.String())
+	}
+
+	return sim.error(cmd, 4, "unimplemented command")
+}
+
+func (sim *Simulator) response(cmd simCommand, attrs, body string) string {
+	if attrs != "" {
+		attrs = " " + attrs
+	}
+	return fmt.Sprintf(`<response xmlns="urn:debugger_protocol_v1" xmlns:xdebug="https://xdebug.org/dbgp/xdebug" command="%s" transaction_id="%s"%s>%s</response>`,
+		cmd.name, xmlAttr(cmd.id), attrs, body)
+}
+
+func (sim *Simulator) error(cmd simCommand, code int, msg string) string {
+	return sim.response(cmd, "", fmt.Sprintf(`<error code="%d"><message>%s</message></error>`, code, cdata(msg)))
+}
+
+func (sim *Simulator) statusAttrs() string {
+	return fmt.Sprintf(`status="%s" reason="ok"`, sim.status)
+}
+
+func (sim *Simulator) featureGet(cmd simCommand) string {
+	values := map[string]string{
+		"language_name":    "PHP",
+		"language_version": "simulated",
+		"protocol_version": "1",
+		"supports_async":   "0",
+		"max_children":     "100",
+		"max_data":         "1024",
+		"max_depth":        "1",
+	}
+	name := cmd.args["-n"]
+	v, ok := values[name]
+	if !ok {
+		return sim.response(cmd, fmt.Sprintf(`feature_name="%s" supported="0"`, xmlAttr(name)), "")
+	}
+	return sim.response(cmd, fmt.Sprintf(`feature_name="%s" supported="1"`, xmlAttr(name)), cdata(v))
+}
+
+// step executes the program up to the target step of the command or to a
+// breakpoint. Output of the executed lines is sent as stream packets.
+func (sim *Simulator) step(cmd simCommand) string {
+	if sim.status == "stopping" {
+		return sim.response(cmd, sim.statusAttrs(), "")
+	}
+	if cmd.name == "break" {
+		if sim.pos < 0 {
+			return sim.response(cmd, sim.statusAttrs(), "")
+		}
+		sim.status = "break"
+		return sim.response(cmd, sim.statusAttrs(), sim.message())
+	}
+
+	depth := -1
+	if sim.pos >= 0 {
+		depth = sim.steps[sim.pos].Depth
+	}
+	target := func(st Step) bool {
+		switch cmd.name {
+		case "step_into":
+			return true
+		case "step_over":
+			return st.Depth <= depth
+		case "step_out":
+			return st.Depth < depth
+		}
+		return false
+	}
+
+	for {
+		if sim.pos >= 0 {
+			sim.output(sim.steps[sim.pos].Output)
+		}
+		sim.pos++
+		if sim.pos == len(sim.steps) {
+			sim.status = "stopping"
+			return sim.response(cmd, sim.statusAttrs(), "")
+		}
+		// breakpoints are hit, and counted, also at the target
+		hit := sim.hitBreakpoint()
+		if hit || (depth < 0 && cmd.name != "run") || target(sim.steps[sim.pos]) {
+			sim.status = "break"
+			return sim.response(cmd, sim.statusAttrs(), sim.message())
+		}
+	}
+}
+
+func (sim *Simulator) message() string {
+	st := sim.steps[sim.pos]
+	return fmt.Sprintf(`<xdebug:message filename="%s" lineno="%d"/>`, xmlAttr(st.File), st.Line)
+}
+
+// output sends the stream packet with the output of a line.
+func (sim *Simulator) output(text string) {
+	if text == "" || !sim.stdout {
+		return
+	}
+	packet := fmt.Sprintf(`<stream xmlns="urn:debugger_protocol_v1" type="stdout" encoding="base64">%s</stream>`,
+		cdata(base64.StdEncoding.EncodeToString([]byte(text))))
+	if err := sim.write(packet); err != nil {
+		log.Println(err)
+	}
+}
+
+// hitBreakpoint returns true if a breakpoint is hit at the current step.
+func (sim *Simulator) hitBreakpoint() bool {
+	st := sim.steps[sim.pos]
+	var prev Step
+	if sim.pos > 0 {
+		prev = sim.steps[sim.pos-1]
+	}
+
+	hit := false
+	for _, bp := range sim.breakpoints {
+		var at bool
+		switch bp.typ {
+		case "line", "conditional":
+			at = bp.file == st.File && bp.line == st.Line
+		case "call":
+			at = sim.pos > 0 && st.Depth > prev.Depth && bp.function == st.Function
+		case "return":
+			// breaks at the line the function returned to
+			at = sim.pos > 0 && st.Depth < prev.Depth && bp.function == prev.Function
+		}
+		if at && bp.expression != "" {
+			v, err := evalSimExpr(sim.locals(0), bp.expression)
+			at = err == nil && v == true
+		}
+		if !at {
+			continue
+		}
+		bp.hitCount++
+		switch bp.hitCondition {
+		case ">=":
+			at = bp.hitCount >= bp.hitValue
+		case "==":
+			at = bp.hitCount == bp.hitValue
+		case "%":
+			at = bp.hitValue > 0 && bp.hitCount%bp.hitValue == 0
+		}
+		hit = hit || at
+	}
+	return hit
+}
+
+func (sim *Simulator) breakpointSet(cmd simCommand) string {
+	bp := &simBreakpoint{
+		typ:          cmd.args["-t"],
+		file:         cmd.args["-f"],
+		function:     cmd.args["-m"],
+		exception:    cmd.args["-x"],
+		expression:   cmd.data,
+		hitCondition: cmd.args["-o"],
+	}
+	bp.line, _ = strconv.Atoi(cmd.args["-n"])
+	bp.hitValue, _ = strconv.Atoi(cmd.args["-h"])
+	if bp.hitCondition == "" && bp.hitValue > 0 {
+		bp.hitCondition = ">="
+	}
+
+	switch bp.typ {
+	case "line", "conditional":
+		if bp.file == "" && sim.pos >= 0 {
+			bp.file = sim.steps[sim.pos].File
+		}
+		if bp.line <= 0 {
+			return sim.error(cmd, 200, "line expected")
+		}
+	case "call", "return":
+		if bp.function == "" {
+			return sim.error(cmd, 200, "function expected")
+		}
+	case "exception":
+		// the simulated program throws no exceptions
+	default:
+		return sim.error(cmd, 201, "breakpoint type not supported")
+	}
+
+	sim.lastID++
+	bp.id = sim.lastID
+	sim.breakpoints = append(sim.breakpoints, bp)
+	return sim.response(cmd, fmt.Sprintf(`state="enabled" id="%d"`, bp.id), "")
+}
+
+func (sim *Simulator) propertySet(cmd simCommand) string {
+	level, ok := sim.level(cmd)
+	if !ok {
+		return sim.error(cmd, 301, "stack depth invalid")
+	}
+	name := cmd.args["-n"]
+	if !simVarRegexp.MatchString(name) {
+		return sim.error(cmd, 300, "only variables can be set")
+	}
+
+	var v interface{} = cmd.data
+	switch cmd.args["-t"] {
+	case "":
+		var err error
+		if v, err = parseSimLiteral(cmd.data); err != nil {
+			return sim.error(cmd, 206, err.Error())
+		}
+	case "int":
+		n, err := strconv.Atoi(cmd.data)
+		if err != nil {
+			return sim.error(cmd, 206, err.Error())
+		}
+		v = n
+	case "float":
+		f, err := strconv.ParseFloat(cmd.data, 64)
+		if err != nil {
+			return sim.error(cmd, 206, err.Error())
+		}
+		v = f
+	case "bool":
+		v = cmd.data == "1" || cmd.data == "true"
+	}
+
+	// the value is assigned at the current step of the frame
+	i := sim.frameStep(level)
+	vars := make(map[string]interface{})
+	for k, old := range sim.steps[i].Vars {
+		vars[k] = old
+	}
+	vars[name] = v
+	sim.steps[i].Vars = vars
+	return sim.response(cmd, `success="1"`, "")
+}
+
+// level returns the stack level of the -d argument.
+func (sim *Simulator) level(cmd simCommand) (int, bool) {
+	level, err := strconv.Atoi(cmd.args["-d"])
+	if cmd.args["-d"] == "" {
+		level, err = 0, nil
+	}
+	return level, err == nil && level >= 0 && level <= sim.steps[sim.pos].Depth
+}
+
+// frameStep returns the current step of the stack frame, the call of the
+// frame above it for levels greater than 0.
+func (sim *Simulator) frameStep(level int) int {
+	depth := sim.steps[sim.pos].Depth - level
+	i := sim.pos
+	for sim.steps[i].Depth != depth {
+		i--
+	}
+	return i
+}
+
+// locals returns the variables of the stack frame: the variables assigned
+// in the function call up to its current step.
+func (sim *Simulator) locals(level int) map[string]interface{} {
+	i := sim.frameStep(level)
+	depth := sim.steps[i].Depth
+	vars := make(map[string]interface{})
+	for ; i >= 0 && sim.steps[i].Depth >= depth; i-- {
+		if sim.steps[i].Depth != depth {
+			continue
+		}
+		for name, v := range sim.steps[i].Vars {
+			if _, ok := vars[name]; !ok {
+				vars[name] = v
+			}
+		}
+	}
+	return vars
+}
+
+// writeSimProperty writes the property element of the value with children
+// down to depth levels.
+func writeSimProperty(b *strings.Builder, name, fullName string, v interface{}, depth int) {
+	attrs := ""
+	if name != "" {
+		attrs = fmt.Sprintf(` name="%s" fullname="%s"`, xmlAttr(name), xmlAttr(fullName))
+	}
+
+	switch v := v.(type) {
+	case nil:
+		fmt.Fprintf(b, `<property%s type="null"></property>`, attrs)
+	case bool:
+		n := 0
+		if v {
+			n = 1
+		}
+		fmt.Fprintf(b, `<property%s type="bool">%s</property>`, attrs, cdata(strconv.Itoa(n)))
+	case int:
+		fmt.Fprintf(b, `<property%s type="int">%s</property>`, attrs, cdata(strconv.Itoa(v)))
+	case float64:
+		fmt.Fprintf(b, `<property%s type="float">%s</property>`, attrs, cdata(strconv.FormatFloat(v, 'g', -1, 64)))
+	case string:
+		fmt.Fprintf(b, `<property%s type="string" size="%d" encoding="base64">%s</property>`,
+			attrs, len(v), cdata(base64.StdEncoding.EncodeToString([]byte(v))))
+	default:
+		keys, values := simChildren(v)
+		if keys == nil {
+			fmt.Fprintf(b, `<property%s type="string" encoding="base64">%s</property>`,
+				attrs, cdata(base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(v)))))
+			return
+		}
+		children := 0
+		if len(keys) > 0 {
+			children = 1
+		}
+		fmt.Fprintf(b, `<property%s type="array" children="%d" numchildren="%d" page="0" pagesize="%d">`,
+			attrs, children, len(keys), len(keys))
+		if depth > 0 {
+			for i, k := range keys {
+				childName := fullName + "[" + k + "]"
+				if _, err := strconv.Atoi(k); err != nil {
+					childName = fullName + "['" + k + "']"
+				}
+				writeSimProperty(b, k, childName, values[i], depth-1)
+			}
+		}
+		b.WriteString(`</property>`)
+	}
+}
+
+// simChildren returns the keys and values of a list or a map, nil for
+// other values. Map keys are sorted.
+func simChildren(v interface{}) ([]string, []interface{}) {
+	keys := []string{}
+	var values []interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		for i, c := range v {
+			keys = append(keys, strconv.Itoa(i))
+			values = append(values, c)
+		}
+	case map[string]interface{}:
+		keys = sortedKeys(v)
+		for _, k := range keys {
+			values = append(values, v[k])
+		}
+	case map[interface{}]interface{}:
+		// maps decoded from YAML
+		m := make(map[string]interface{})
+		for k, c := range v {
+			m[fmt.Sprint(k)] = c
+		}
+		return simChildren(m)
+	default:
+		return nil, nil
+	}
+	return keys, values
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var (
+	simVarRegexp   = regexp.MustCompile(`^\$\w+$`)
+	simNameRegexp  = regexp.MustCompile(`^(\$\w+)((\[[^\]]*\])*)$`)
+	simIndexRegexp = regexp.MustCompile(`\[([^\]]*)\]`)
+)
+
+// lookupSimValue returns the value of the variable name like $a['b'][0].
+func lookupSimValue(vars map[string]interface{}, name string) (interface{}, error) {
+	m := simNameRegexp.FindStringSubmatch(name)
+	if m == nil {
+		return nil, fmt.Errorf("can not get property %s", name)
+	}
+	v, ok := vars[m[1]]
+	if !ok {
+		return nil, fmt.Errorf("property %s does not exist", m[1])
+	}
+	for _, idx := range simIndexRegexp.FindAllStringSubmatch(m[2], -1) {
+		key := strings.Trim(idx[1], `'"`)
+		keys, values := simChildren(v)
+		found := false
+		for i, k := range keys {
+			if k == key {
+				v, found = values[i], true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("property %s does not exist", name)
+		}
+	}
+	return v, nil
+}
+
+// simOperators are the comparisons of evalSimExpr, longer ones first.
+var simOperators = []string{"===", "!==", "==", "!=", "<=", ">=", "<", ">"}
+
+// evalSimExpr evaluates a variable, a literal or a comparison of them.
+func evalSimExpr(vars map[string]interface{}, expr string) (interface{}, error) {
+	expr = strings.TrimSpace(expr)
+	for _, op := range simOperators {
+		i := strings.Index(expr, op)
+		if i < 0 {
+			continue
+		}
+		a, err := evalSimExpr(vars, expr[:i])
+		if err != nil {
+			return nil, err
+		}
+		b, err := evalSimExpr(vars, expr[i+len(op):])
+		if err != nil {
+			return nil, err
+		}
+		return compareSim(a, b, op), nil
+	}
+
+	if strings.HasPrefix(expr, "$") {
+		return lookupSimValue(vars, expr)
+	}
+	return parseSimLiteral(expr)
+}
+
+func compareSim(a, b interface{}, op string) bool {
+	fa, aNum := simNumber(a)
+	fb, bNum := simNumber(b)
+	c := 0
+	if aNum && bNum {
+		if fa < fb {
+			c = -1
+		} else if fa > fb {
+			c = 1
+		}
+	} else {
+		c = strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+
+	switch op {
+	case "===", "==":
+		return c == 0
+	case "!==", "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case ">":
+		return c > 0
+	case "<=":
+		return c <= 0
+	}
+	return c >= 0
+}
+
+func simNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// parseSimLiteral parses a PHP number, quoted string, bool or null.
+func parseSimLiteral(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	switch strings.ToLower(s) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1], nil
+	}
+	return nil, fmt.Errorf("error evaluating code: %s", s)
+}
+
+func xmlAttr(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func cdata(s string) string {
+	return "<![CDATA[" + strings.Replace(s, "]]>", "]]]]><![CDATA[>", -1) + "]]>"
+}
+
+// demoCmd starts the demo with the program file, or with the built-in
+// program written to the temp dir.
+func (xc *Client) demoCmd(args []string) error {
+	if xc.started {
+		return fmt.Errorf("phpdebug already started")
+	}
+
+	var p *Program
+	var err error
+	if len(args) > 0 {
+		p, err = ReadProgram(args[0])
+	} else {
+		p, err = DemoProgram(filepath.Join(os.TempDir(), "micro-php-demo"))
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := xc.Demo(p); err != nil {
+		return err
+	}
+	xc.started = true
+	return nil
+}
+
+// serveEngine listens on a loopback port and starts the fake engine
+// connecting to it, dial is called in the background.
+func (xc *Client) serveEngine(dial func(network, addr string) error) error {
+	if xc.listener != nil {
+		return fmt.Errorf("phpdebug is already listening")
+	}
+
+	l, err := listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	xc.listener = l
+	// fake engines do not run launch configurations
+	xc.launch = nil
+
+	go xc.waitConnection(l, demoTimeout, "")
+	go func() {
+		if err := dial("tcp", l.Addr().String()); err != nil {
+			log.Println(err)
+		}
+	}()
+	return nil
+}
+
+// Demo starts a debug session with the simulator executing the program in
+// place of a PHP engine.
+func (xc *Client) Demo(p *Program) error {
+	if err := xc.serveEngine(NewSimulator(p).Dial); err != nil {
+		return err
+	}
+	xc.Editor.Message("demo: simulating PHP running ", path.Base(p.Steps[0].File))
+	return nil
+}
@@ -0,0 +1,200 @@
+package xdebug
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+	"github.com/zyedidia/micro/v2/internal/shell"
+)
+
+// runUntil runs callbacks posted to the main loop until cond is true.
+func runUntil(t *testing.T, cond func() bool) {
+	deadline := time.After(2 * time.Second)
+	for !cond() {
+		select {
+		case f := <-shell.Jobs:
+			f.Function(f.Output, f.Args)
+		case <-deadline:
+			t.Fatal("timeout waiting for the simulated session")
+		}
+	}
+}
+
+// drainJobs runs the callbacks posted until the main loop is idle, so that
+// callbacks of a closed connection do not reach other tests.
+func drainJobs() {
+	for {
+		select {
+		case f := <-shell.Jobs:
+			f.Function(f.Output, f.Args)
+		case <-time.After(100 * time.Millisecond):
+			return
+		}
+	}
+}
+
+func TestReadProgram(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xdebug")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "prog.yaml")
+	assert.NoError(t, ioutil.WriteFile(fname, []byte(`
+files:
+  a.php: "<?php\nf(1);\n"
+steps:
+  - file: a.php
+    line: 2
+  - function: f
+    depth: 1
+    line: 7
+    vars: {$x: 1, $list: [a, b], $map: {k: v}}
+  - line: 3
+`), 0644))
+
+	p, err := ReadProgram(fname)
+	assert.NoError(t, err)
+	uri := pathToURI(filepath.Join(dir, "a.php"))
+	assert.Equal(t, map[string]string{uri: "<?php\nf(1);\n"}, p.Files)
+	assert.Equal(t, Step{File: uri, Line: 2, Function: "{main}"}, p.Steps[0])
+	// calls continue in the file of the caller
+	assert.Equal(t, uri, p.Steps[1].File)
+	assert.Equal(t, "f", p.Steps[1].Function)
+	assert.Equal(t, "{main}", p.Steps[2].Function)
+
+	assert.NoError(t, ioutil.WriteFile(fname, []byte("steps:\n  - {file: a.php, line: 1, depth: 2}\n"), 0644))
+	_, err = ReadProgram(fname)
+	assert.EqualError(t, err, fname+": step 1: depth 2, expected 0 to 0")
+}
+
+func TestParseSimCommand(t *testing.T) {
+	cmd, err := parseSimCommand(`property_set -i 12 -n "$a[\"b c\"]" -d 0 -- MQ==`)
+	assert.NoError(t, err)
+	assert.Equal(t, "property_set", cmd.name)
+	assert.Equal(t, "12", cmd.id)
+	assert.Equal(t, `$a["b c"]`, cmd.args["-n"])
+	assert.Equal(t, "1", cmd.data)
+
+	_, err = parseSimCommand("run -i")
+	assert.Error(t, err)
+}
+
+func TestEvalSimExpr(t *testing.T) {
+	vars := map[string]interface{}{
+		"$n":    3,
+		"$list": []interface{}{"a", 2},
+		"$map":  map[interface{}]interface{}{"k": "v"},
+	}
+	for expr, want := range map[string]interface{}{
+		"$n":               3,
+		"$list[1]":         2,
+		"$map['k']":        "v",
+		"$n >= 3":          true,
+		"$n == 4":          false,
+		`$map["k"] == 'v'`: true,
+		"1.5":              1.5,
+		"null":             nil,
+	} {
+		v, err := evalSimExpr(vars, expr)
+		assert.NoError(t, err, expr)
+		assert.Equal(t, want, v, expr)
+	}
+
+	_, err := evalSimExpr(vars, "$nope")
+	assert.EqualError(t, err, "property $nope does not exist")
+	_, err = evalSimExpr(vars, "f()")
+	assert.Error(t, err)
+}
+
+func TestDemoSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xdebug")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	p, err := DemoProgram(dir)
+	assert.NoError(t, err)
+	index := filepath.Join(dir, "index.php")
+	greet := filepath.Join(dir, "greet.php")
+
+	ed := &testEditor{}
+	xc := &Client{Editor: ed, Config: Config{BreakFirst: true}}
+	xc.SetBreakpoints(greet, []int{6})
+	xc.SetWatches([]string{"$count"})
+	assert.NoError(t, xc.Demo(p))
+	xc.started = true
+
+	at := func(fname string, line int) func() bool {
+		return func() bool {
+			// the location is set before the stack and the variables
+			uri, l := xc.Location()
+			stack := xc.Stack()
+			w := xc.Watches()[0]
+			return uri == pathToURI(fname) && l == line && len(stack) > 0 && stack[0].Line == line &&
+				len(xc.Contexts()) > 0 && (w.Value != nil || w.Error != "")
+		}
+	}
+
+	// the first line
+	runUntil(t, at(index, 2))
+	assert.Equal(t, "{main}", xc.Stack()[0].Where)
+
+	assert.NoError(t, xc.ProcessCommand([]string{"n"}))
+	runUntil(t, at(index, 4))
+
+	// the breakpoint in the function
+	assert.NoError(t, xc.ProcessCommand([]string{"c"}))
+	runUntil(t, at(greet, 6))
+	stack := xc.Stack()
+	assert.Len(t, stack, 2)
+	assert.Equal(t, debugger.Frame{Level: 0, File: pathToURI(greet), Line: 6, Where: "greet"}, stack[0])
+	assert.Equal(t, debugger.Frame{Level: 1, File: pathToURI(index), Line: 7, Where: "{main}"}, stack[1])
+	runUntil(t, func() bool { return len(xc.Contexts()[0].Properties) == 2 })
+	props := xc.Contexts()[0].Properties
+	assert.Equal(t, "$greeting", props[0].Name)
+	assert.Equal(t, "Hello, Ada", props[0].Value)
+	assert.Equal(t, "$name", props[1].Name)
+
+	var value *debugger.Property
+	assert.NoError(t, xc.Eval("$greeting", func(p *debugger.Property, err error) {
+		assert.NoError(t, err)
+		value = p
+	}))
+	runUntil(t, func() bool { return value != nil })
+	assert.Equal(t, "string", value.Type)
+	assert.Equal(t, "Hello, Ada", value.Value)
+
+	// variables of the caller
+	assert.NoError(t, xc.SelectFrame(1))
+	runUntil(t, func() bool {
+		c := xc.Contexts()
+		return len(c) > 0 && len(c[0].Properties) == 3
+	})
+	names := xc.Contexts()[0].Properties[2]
+	assert.Equal(t, "$names", names.Name)
+	assert.Equal(t, 3, names.NumChildren)
+	assert.Equal(t, "$names[2]", names.Children[2].FullName)
+	assert.Equal(t, "Linus", names.Children[2].Value)
+
+	// the next hit, output of the first call is copied
+	assert.NoError(t, xc.ProcessCommand([]string{"c"}))
+	runUntil(t, func() bool {
+		c := xc.Contexts()
+		return at(greet, 6)() && len(c[0].Properties) == 2 && c[0].Properties[1].Value == "Grace"
+	})
+	assert.Equal(t, "Hello, Ada!\n", ed.output[1])
+	// $count is not a local of the function
+	assert.Equal(t, "property $count does not exist", xc.Watches()[0].Error)
+
+	// without breakpoints the program runs to the end
+	xc.SetBreakpoints(greet, nil)
+	assert.NoError(t, xc.ProcessCommand([]string{"c"}))
+	runUntil(t, func() bool { return xc.Status() == "" })
+	assert.Equal(t, "Hello, Ada!\nHello, Grace!\nHello, Linus!\ngreeted 3 people\n", ed.output[1])
+	assert.Empty(t, ed.errors)
+
+	drainJobs()
+}
@@ -0,0 +1,43 @@
+package xdebug
+
+import (
+	"fmt"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// Stack returns stack frames of the current break, the innermost first.
+func (xc *Client) Stack() []debugger.Frame {
+	if xc.current == nil {
+		return nil
+	}
+	return xc.current.stack
+}
+
+// Depth returns the level of the selected stack frame.
+func (xc *Client) Depth() int {
+	if xc.current == nil {
+		return 0
+	}
+	return xc.current.depth
+}
+
+// SelectFrame opens the file of the stack frame at its line and switches
+// variables to the frame.
+func (xc *Client) SelectFrame(level int) error {
+	s := xc.current
+	if s == nil || level < 0 || level >= len(s.stack) {
+		return fmt.Errorf("no stack frame %d", level)
+	}
+
+	f := s.stack[level]
+	s.jumpTo(f.File, f.Line)
+
+	if s.depth != level {
+		s.depth = level
+		s.refreshVariables()
+	}
+	xc.Editor.StackChanged()
+
+	return nil
+}
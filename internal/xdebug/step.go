@@ -0,0 +1,117 @@
+package xdebug
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/zyedidia/glob"
+)
+
+// RunTo continues the current session to the line of the local file. A
+// temporary breakpoint is set at the line, it is removed at the next break.
+func (xc *Client) RunTo(fname string, line int) error {
+	s := xc.current
+	if s == nil || s.status != "break" {
+		return fmt.Errorf("run to cursor: the debugger is not stopped at a break")
+	}
+
+	if !xc.hasBreakpoint(fname, line) {
+		uri, err := xc.remoteURI(fname)
+		if err != nil {
+			return err
+		}
+		args := fmt.Sprintf("-t line -f %s -n %d", uri, line)
+		err = s.command("breakpoint_set", args, nil, func(resp Response) {
+			s.runTo = resp.ID
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.step("run"); err != nil {
+		return err
+	}
+	s.status = "running"
+	xc.Editor.SessionsChanged()
+	xc.Editor.LocationChanged()
+	xc.Editor.Message(fmt.Sprintf("running to %s:%d", filepath.Base(fname), line))
+	return nil
+}
+
+// removeRunTo removes the temporary breakpoint of RunTo.
+func (s *session) removeRunTo() {
+	if s.runTo == 0 {
+		return
+	}
+	args := fmt.Sprintf("-d %d", s.runTo)
+	s.runTo = 0
+	// the engine may have removed it already, errors are only logged
+	_, err := s.conn.send("breakpoint_remove", args, nil, func(resp Response) {
+		if resp.Error.Code != 0 {
+			log.Printf("breakpoint_remove %s: error %d: %s", args, resp.Error.Code, resp.Error.Message.Text)
+		}
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// stepInto steps into the next statement. Breaks in files matching the
+// step filters are left with step_out until the script is back in other
+// files.
+func (s *session) stepInto() error {
+	if err := s.step("step_into"); err != nil {
+		return err
+	}
+	s.filterSteps = len(s.xc.StepFilters) > 0
+	return nil
+}
+
+// skipFiltered steps out of the file of the break if it matches the step
+// filters after stepInto. It returns true if the break is skipped.
+func (s *session) skipFiltered(uri string, line int) bool {
+	if !s.filterSteps {
+		return false
+	}
+	fname, err := s.xc.LocalPath(uri)
+	if err != nil || s.xc.hasBreakpoint(fname, line) || !s.xc.filtered(fname) {
+		s.filterSteps = false
+		return false
+	}
+
+	log.Println("step filter: skip", fname, line)
+	if err := s.command("step_out", "", nil, nil); err != nil {
+		log.Println(err)
+		s.xc.Editor.Error(err)
+		s.filterSteps = false
+		return false
+	}
+	return true
+}
+
+// filtered returns true if the local file matches one of the step filters.
+// Filters are globs of paths relative to the project root, or of absolute
+// paths, e.g. vendor/** or /usr/share/php/*.
+func (xc *Client) filtered(fname string) bool {
+	if !filepath.IsAbs(fname) {
+		fname = filepath.Join(xc.root(), fname)
+	}
+	rel, err := filepath.Rel(xc.root(), fname)
+	if err != nil {
+		rel = fname
+	}
+
+	for _, f := range xc.StepFilters {
+		g, err := glob.Compile(f)
+		if err != nil {
+			log.Printf("step filter %q: %v", f, err)
+			continue
+		}
+		if g.MatchString(filepath.ToSlash(rel)) || g.MatchString(filepath.ToSlash(fname)) {
+			return true
+		}
+	}
+	return false
+}
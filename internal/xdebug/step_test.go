@@ -0,0 +1,64 @@
+package xdebug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepFilters(t *testing.T) {
+	xc := &Client{Root: "/srv/app", Config: Config{StepFilters: []string{"vendor/**", "/usr/share/php/*", "lib/{a,b}.php"}}}
+	assert.True(t, xc.filtered("/srv/app/vendor/laravel/framework/src/App.php"))
+	assert.True(t, xc.filtered("vendor/autoload.php"))
+	assert.True(t, xc.filtered("/usr/share/php/Foo.php"))
+	assert.True(t, xc.filtered("/srv/app/lib/b.php"))
+	assert.False(t, xc.filtered("/srv/app/src/vendor.php"))
+	assert.False(t, xc.filtered("/srv/app/lib/c.php"))
+
+	xc, s, engine := testSession(t)
+	defer engine.Close()
+	cmds := engineCommands(engine)
+	xc.Root = "/srv/app"
+	xc.StepFilters = []string{"vendor/**"}
+
+	assert.NoError(t, xc.ProcessCommand([]string{"s"}))
+	assert.Equal(t, "step_into -i 1", nextCommand(t, cmds))
+
+	// breaks in filtered files are stepped out of
+	writePacket(t, engine, `<response command="step_into" transaction_id="1" status="break"><xdebug:message filename="file:///srv/app/vendor/a.php" lineno="3"/></response>`)
+	runJob(t)
+	assert.Equal(t, "step_out -i 2", nextCommand(t, cmds))
+	writePacket(t, engine, `<response command="step_out" transaction_id="2" status="break"><xdebug:message filename="file:///srv/app/vendor/b.php" lineno="7"/></response>`)
+	runJob(t)
+	assert.Equal(t, "step_out -i 3", nextCommand(t, cmds))
+
+	writePacket(t, engine, `<response command="step_out" transaction_id="3" status="break"><xdebug:message filename="file:///srv/app/index.php" lineno="5"/></response>`)
+	runJob(t)
+	assert.Equal(t, "source -i 4 -f file:///srv/app/index.php", nextCommand(t, cmds))
+	assert.Equal(t, "stack_get -i 5", nextCommand(t, cmds))
+	assert.False(t, s.filterSteps)
+}
+
+func TestRunTo(t *testing.T) {
+	xc, s, engine := testSession(t)
+	defer engine.Close()
+	cmds := engineCommands(engine)
+
+	assert.NoError(t, xc.RunTo("/srv/app/index.php", 12))
+	assert.Equal(t, "breakpoint_set -i 1 -t line -f file:///srv/app/index.php -n 12", nextCommand(t, cmds))
+	assert.Equal(t, "run -i 2", nextCommand(t, cmds))
+	writePacket(t, engine, `<response command="breakpoint_set" transaction_id="1" id="42"/>`)
+	runJob(t)
+	assert.Equal(t, 42, s.runTo)
+
+	// the temporary breakpoint is removed at the next break
+	writePacket(t, engine, `<response command="run" transaction_id="2" status="break"><xdebug:message filename="file:///srv/app/index.php" lineno="12"/></response>`)
+	runJob(t)
+	assert.Equal(t, "breakpoint_remove -i 3 -d 42", nextCommand(t, cmds))
+	assert.Equal(t, "source -i 4 -f file:///srv/app/index.php", nextCommand(t, cmds))
+	assert.Equal(t, "stack_get -i 5", nextCommand(t, cmds))
+	assert.Equal(t, 0, s.runTo)
+
+	s.status = "running"
+	assert.Error(t, xc.RunTo("/srv/app/index.php", 12))
+}
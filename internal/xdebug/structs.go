@@ -10,30 +10,54 @@ import (
 )
 
 type property struct {
-	Type       string     `xml:"type,attr"`
-	Name       string     `xml:"name,attr"`
-	ClassName  string     `xml:"classname,attr"`
-	Encoding   string     `xml:"encoding,attr"`
-	Text       string     `xml:",cdata"`
-	Properties []property `xml:"property"`
+	Type        string     `xml:"type,attr"`
+	Name        string     `xml:"name,attr"`
+	FullName    string     `xml:"fullname,attr"`
+	ClassName   string     `xml:"classname,attr"`
+	Encoding    string     `xml:"encoding,attr"`
+	Children    int        `xml:"children,attr"`
+	NumChildren int        `xml:"numchildren,attr"`
+	Page        int        `xml:"page,attr"`
+	PageSize    int        `xml:"pagesize,attr"`
+	Size        int        `xml:"size,attr"`
+	Text        string     `xml:",cdata"`
+	Properties  []property `xml:"property"`
 }
 
 type breakpoint struct {
-	Type     string `xml:"type,attr"`
-	Filename string `xml:"filename,attr"`
-	Line     int    `xml:"lineno,attr"`
-	State    string `xml:"state,attr"`
-	HitCount int    `xml:"hitcount,attr"`
-	HitValue int    `xml:"hitvalue,attr"`
-	ID       int    `xml:"id,attr"`
+	Type         string `xml:"type,attr"`
+	Filename     string `xml:"filename,attr"`
+	Line         int    `xml:"lineno,attr"`
+	State        string `xml:"state,attr"`
+	Resolved     string `xml:"resolved,attr"`
+	Function     string `xml:"function,attr"`
+	Exception    string `xml:"exception,attr"`
+	HitCount     int    `xml:"hit_count,attr"`
+	HitValue     int    `xml:"hit_value,attr"`
+	HitCondition string `xml:"hit_condition,attr"`
+	ID           int    `xml:"id,attr"`
+	Expression   struct {
+		Encoding string `xml:"encoding,attr"`
+		Text     string `xml:",cdata"`
+	} `xml:"expression"`
 }
 
+// Response is a DBGp packet from the engine: a command response, or a notify
+// or stream packet distinguished by XMLName.
 type Response struct {
+	XMLName  xml.Name
 	Command  string `xml:"command,attr"`
 	TrID     int    `xml:"transaction_id,attr"`
 	Encoding string `xml:"encoding,attr"`
 	Status   string `xml:"status,attr"`
 	Reason   string `xml:"reason,attr"`
+	ID       int    `xml:"id,attr"`      // breakpoint_set id
+	Name     string `xml:"name,attr"`    // notify name
+	Type     string `xml:"type,attr"`    // stream type: stdout or stderr
+	IDEKey   string `xml:"idekey,attr"`  // init
+	FileURI  string `xml:"fileuri,attr"` // init
+	Success  int    `xml:"success,attr"` // property_set and feature_set
+	Support  int    `xml:"supported,attr"`
 	Text     string `xml:",cdata"`
 	Error    struct {
 		Code    int `xml:"code,attr"`
@@ -53,8 +77,14 @@ type Response struct {
 		CmdBegin string `xml:"cmdbegin,attr"`
 		CmdEnd   string `xml:"cmdend,attr"`
 	} `xml:"stack"`
-	Properties  []property   `xml:"property"`
+	Properties []property `xml:"property"`
+	Contexts   []struct {
+		ID   int    `xml:"id,attr"`
+		Name string `xml:"name,attr"`
+	} `xml:"context"`
 	Breakpoints []breakpoint `xml:"breakpoint"`
+
+	packet []byte // raw init packet for transcripts
 }
 
 func unmarshalCommand(b []byte) (Response, error) {
@@ -79,4 +109,8 @@ type proxyResponse struct {
 	Address string `xml:"address,attr"`
 	Port    int    `xml:"port,attr"`
 	Ssl     bool   `xml:"ssl,attr"`
+	Error   struct {
+		ID      int    `xml:"id,attr"`
+		Message string `xml:"message"`
+	} `xml:"error"`
 }
@@ -0,0 +1,227 @@
+package xdebug
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Trace is a function trace written by Xdebug in the computerized format
+// (xdebug.trace_format=1), e.g. trace.1234.xt.
+type Trace struct {
+	Version string
+	Start   string // TRACE START time
+	End     string // TRACE END time
+	Calls   []*TraceCall
+}
+
+// TraceCall is a function call of the trace with the calls it made.
+type TraceCall struct {
+	Name     string
+	Internal bool     // PHP function, not user defined
+	Include  string   // file of include and require calls
+	File     string   // file of the call site
+	Line     int      // line of the call site
+	Args     []string // arguments if the trace collects them
+	Return   string   // return value if the trace collects them
+	Children []*TraceCall
+	Expanded bool
+
+	Level       int
+	EntryTime   float64 // seconds since the start of the trace
+	ExitTime    float64 // 0 if the call did not return before the trace ended
+	EntryMemory int64
+	ExitMemory  int64
+}
+
+// Duration returns the time spent in the call in seconds.
+func (c *TraceCall) Duration() float64 {
+	if c.ExitTime == 0 {
+		return 0
+	}
+	return c.ExitTime - c.EntryTime
+}
+
+// MemoryDelta returns the change of memory usage during the call.
+func (c *TraceCall) MemoryDelta() int64 {
+	if c.ExitTime == 0 {
+		return 0
+	}
+	return c.ExitMemory - c.EntryMemory
+}
+
+// String returns the call with its arguments and return value.
+func (c *TraceCall) String() string {
+	s := c.Name
+	if c.Include != "" {
+		s += " " + c.Include
+	} else {
+		s += "(" + strings.Join(c.Args, ", ") + ")"
+	}
+	if c.Return != "" {
+		s += " = " + c.Return
+	}
+	return s
+}
+
+// ReadTrace reads the trace file, which may be gzip compressed.
+func ReadTrace(fname string) (*Trace, error) {
+	var t *Trace
+	err := readFile(fname, func(r io.Reader) error {
+		var err error
+		t, err = ParseTrace(r)
+		return err
+	})
+	return t, err
+}
+
+// FindTrace returns the newest trace.*.xt file of the directories or "" if
+// there is none.
+func FindTrace(dirs ...string) string {
+	return findNewest("trace.*.xt*", dirs)
+}
+
+// LoadTrace reads the trace file and maps its engine files to local files
+// with the path mappings of the project.
+func (xc *Client) LoadTrace(fname string) (*Trace, error) {
+	if err := xc.readConfig(); err != nil {
+		return nil, err
+	}
+	t, err := ReadTrace(fname)
+	if err != nil {
+		return nil, err
+	}
+	var mapCalls func(calls []*TraceCall)
+	mapCalls = func(calls []*TraceCall) {
+		for _, c := range calls {
+			c.File = xc.localName(c.File)
+			mapCalls(c.Children)
+		}
+	}
+	mapCalls(t.Calls)
+	return t, nil
+}
+
+// ParseTrace parses a trace in the computerized format. Entry records are
+//
+//	level, function #, 0, time, memory, name, user-defined, include file, file, line, args count, args...
+//
+// exit records are level, function #, 1, time, memory and return records
+// are level, function #, R, "", "", return value.
+func ParseTrace(r io.Reader) (*Trace, error) {
+	t := &Trace{}
+	calls := make(map[string]*TraceCall) // function # -> call
+	var stack []*TraceCall
+	started := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		l := scanner.Text()
+		switch {
+		case strings.HasPrefix(l, "Version: "):
+			t.Version = strings.TrimPrefix(l, "Version: ")
+			continue
+		case strings.HasPrefix(l, "TRACE START"):
+			t.Start = traceTime(l)
+			started = true
+			continue
+		case strings.HasPrefix(l, "TRACE END"):
+			t.End = traceTime(l)
+			continue
+		case !started || strings.TrimSpace(l) == "" || strings.HasPrefix(l, "\t"):
+			// headers and the summary line of the end time and memory
+			continue
+		}
+
+		fields := strings.Split(l, "\t")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: short record", n)
+		}
+		level, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: level. error: %w", n, err)
+		}
+
+		switch fields[2] {
+		case "0":
+			c, err := parseTraceEntry(fields)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			c.Level = level
+			calls[fields[1]] = c
+
+			for len(stack) >= level && len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				c.Expanded = true
+				t.Calls = append(t.Calls, c)
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, c)
+			}
+			stack = append(stack, c)
+		case "1":
+			c := calls[fields[1]]
+			if c == nil || len(fields) < 5 {
+				continue
+			}
+			c.ExitTime, _ = strconv.ParseFloat(fields[3], 64)
+			c.ExitMemory, _ = strconv.ParseInt(fields[4], 10, 64)
+		case "R":
+			if c := calls[fields[1]]; c != nil && len(fields) >= 6 {
+				c.Return = fields[5]
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown record type %q", n, fields[2])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("trace read. error: %w", err)
+	}
+	if !started {
+		return nil, fmt.Errorf("not an Xdebug trace")
+	}
+	return t, nil
+}
+
+func parseTraceEntry(fields []string) (*TraceCall, error) {
+	if len(fields) < 10 {
+		return nil, fmt.Errorf("short entry record")
+	}
+	c := &TraceCall{
+		Name:     fields[5],
+		Internal: fields[6] == "0",
+		Include:  fields[7],
+		File:     fields[8],
+	}
+	var err error
+	if c.EntryTime, err = strconv.ParseFloat(fields[3], 64); err != nil {
+		return nil, fmt.Errorf("time. error: %w", err)
+	}
+	if c.EntryMemory, err = strconv.ParseInt(fields[4], 10, 64); err != nil {
+		return nil, fmt.Errorf("memory. error: %w", err)
+	}
+	if c.Line, err = strconv.Atoi(fields[9]); err != nil {
+		return nil, fmt.Errorf("line. error: %w", err)
+	}
+	if len(fields) > 10 {
+		c.Args = fields[11:]
+	}
+	return c, nil
+}
+
+// traceTime returns the time in brackets of TRACE START and TRACE END.
+func traceTime(l string) string {
+	i, j := strings.IndexByte(l, '['), strings.LastIndexByte(l, ']')
+	if i < 0 || j < i {
+		return ""
+	}
+	return l[i+1 : j]
+}
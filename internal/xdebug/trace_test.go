@@ -0,0 +1,69 @@
+package xdebug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTrace = `Version: 3.1.0
+File format: 4
+TRACE START [2021-01-01 10:00:00.000000]
+1	0	0	0.000100	393000	{main}	1		/var/www/index.php	0	0
+2	1	0	0.000200	393100	foo	1		/var/www/index.php	5	2	'x'	2
+3	2	0	0.000250	393200	strlen	0		/var/www/lib.php	3	1	'x'
+3	2	1	0.000260	393200
+3	2	R			1
+2	1	1	0.000300	393164
+2	1	R			'ret'
+2	3	0	0.000310	393164	require	1	/var/www/lib.php	/var/www/index.php	7	0
+2	3	1	0.000400	400000
+1	0	1	0.000500	392000
+			0.000600	8192
+TRACE END   [2021-01-01 10:00:00.000600]
+
+`
+
+func TestParseTrace(t *testing.T) {
+	tr, err := ParseTrace(strings.NewReader(testTrace))
+	assert.NoError(t, err)
+	assert.Equal(t, "3.1.0", tr.Version)
+	assert.Equal(t, "2021-01-01 10:00:00.000000", tr.Start)
+	assert.Equal(t, "2021-01-01 10:00:00.000600", tr.End)
+
+	assert.Len(t, tr.Calls, 1)
+	main := tr.Calls[0]
+	assert.Equal(t, "{main}", main.Name)
+	assert.True(t, main.Expanded)
+	assert.InDelta(t, 0.0004, main.Duration(), 1e-9)
+	assert.Equal(t, int64(-1000), main.MemoryDelta())
+	assert.Len(t, main.Children, 2)
+
+	foo := main.Children[0]
+	assert.Equal(t, "foo('x', 2) = 'ret'", foo.String())
+	assert.Equal(t, "/var/www/index.php", foo.File)
+	assert.Equal(t, 5, foo.Line)
+	assert.Equal(t, int64(64), foo.MemoryDelta())
+	assert.False(t, foo.Expanded)
+
+	strlen := foo.Children[0]
+	assert.True(t, strlen.Internal)
+	assert.Equal(t, 3, strlen.Level)
+	assert.Equal(t, "strlen('x') = 1", strlen.String())
+
+	assert.Equal(t, "require /var/www/lib.php", main.Children[1].String())
+}
+
+func TestParseTraceErrors(t *testing.T) {
+	_, err := ParseTrace(strings.NewReader("hello\n"))
+	assert.EqualError(t, err, "not an Xdebug trace")
+
+	_, err = ParseTrace(strings.NewReader("TRACE START [x]\n1\t0\t0\n"))
+	assert.EqualError(t, err, "line 2: short entry record")
+
+	// calls which did not return have no duration
+	tr, err := ParseTrace(strings.NewReader("TRACE START [x]\n1\t0\t0\t0.1\t100\tf\t1\t\t/a.php\t2\t0\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, tr.Calls[0].Duration())
+}
@@ -0,0 +1,305 @@
+package xdebug
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TranscriptEntry is a DBGp packet of a recorded session. Transcripts are
+// files of entries as JSON lines.
+type TranscriptEntry struct {
+	Time    time.Time `json:"time"`
+	Session int       `json:"session"`
+	Dir     string    `json:"dir"`            // send or recv
+	Type    string    `json:"type,omitempty"` // received packet: init, response, stream or notify
+	Command string    `json:"command,omitempty"`
+	TrID    int       `json:"transaction_id,omitempty"`
+	Packet  string    `json:"packet"` // command or XML without the terminating NUL
+}
+
+// recorder appends the packets of all sessions to the transcript file.
+// Packets are recorded from the main loop and the reader goroutines.
+type recorder struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+func newRecorder(fname string) (*recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+		return nil, fmt.Errorf("transcript dir. error: %w", err)
+	}
+	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("transcript open. error: %w", err)
+	}
+	return &recorder{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// session returns the function recording the packets of the session.
+func (r *recorder) session(id int) func(TranscriptEntry) {
+	return func(e TranscriptEntry) {
+		e.Time = time.Now()
+		e.Session = id
+		r.write(e)
+	}
+}
+
+func (r *recorder) write(e TranscriptEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// packets can be read after the recorder is closed
+	if r.f == nil {
+		return
+	}
+	if err := r.enc.Encode(e); err != nil {
+		log.Println("transcript write. error:", err)
+	}
+}
+
+func (r *recorder) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// record starts recording the session to the transcript file if it is set.
+// The init packet is recorded first.
+func (xc *Client) record(s *session, init Response) {
+	if xc.Record == "" {
+		return
+	}
+	if xc.recorder == nil {
+		r, err := newRecorder(xc.Record)
+		if err != nil {
+			log.Println(err)
+			xc.Editor.Error(err)
+			return
+		}
+		xc.recorder = r
+	}
+
+	s.conn.record = xc.recorder.session(s.id)
+	s.conn.record(TranscriptEntry{Dir: "recv", Type: "init", Packet: string(init.packet)})
+}
+
+// stopRecording closes the transcript file.
+func (xc *Client) stopRecording() {
+	if xc.recorder == nil {
+		return
+	}
+	if err := xc.recorder.close(); err != nil {
+		log.Println(err)
+	}
+	xc.recorder = nil
+}
+
+// ReadTranscript reads the entries of the transcript file.
+func ReadTranscript(fname string) ([]TranscriptEntry, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, fmt.Errorf("transcript read. error: %w", err)
+	}
+	defer f.Close()
+
+	var entries []TranscriptEntry
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var e TranscriptEntry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: entry %d. error: %w", fname, len(entries)+1, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Replayer is a fake DBGp engine answering the commands of the client with
+// the responses of a recorded session. A command gets the response of the
+// first unanswered recorded command with the same name, preferably with the
+// same arguments. Streams and notifications are sent before the response
+// they were received before. The timing of the recording is not replayed,
+// so a replay reproduces the session as long as the same commands are run.
+type Replayer struct {
+	entries  []TranscriptEntry // entries of the session
+	answered map[int]bool      // indexes of the answered send entries
+	sent     int               // entries before this index are sent or answered
+}
+
+// NewReplayer returns a replayer of the session of the transcript, the
+// first session if session is 0.
+func NewReplayer(entries []TranscriptEntry, session int) (*Replayer, error) {
+	if session == 0 && len(entries) > 0 {
+		session = entries[0].Session
+	}
+	r := &Replayer{answered: make(map[int]bool)}
+	for _, e := range entries {
+		if e.Session == session {
+			r.entries = append(r.entries, e)
+		}
+	}
+	if len(r.entries) == 0 || r.entries[0].Type != "init" {
+		return nil, fmt.Errorf("no recorded session %d", session)
+	}
+	return r, nil
+}
+
+// Dial connects to the client listening on addr and serves it until the
+// connection is closed.
+func (r *Replayer) Dial(network, addr string) error {
+	conn, err := net.DialTimeout(network, addr, demoTimeout)
+	if err != nil {
+		return fmt.Errorf("replay connect. error: %w", err)
+	}
+	defer conn.Close()
+	return r.Serve(conn)
+}
+
+// Serve sends the recorded init packet on rw and answers the commands until
+// the connection is closed.
+func (r *Replayer) Serve(rw io.ReadWriter) error {
+	r.sent = 1
+	if err := writeReplayPacket(rw, r.entries[0].Packet); err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(rw)
+	for {
+		s, err := br.ReadString(0)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("replay read. error: %w", err)
+		}
+		cmd, err := parseSimCommand(strings.TrimSuffix(s, "\x00"))
+		if err != nil {
+			log.Println("replay:", err)
+			continue
+		}
+
+		for _, packet := range r.answer(cmd, strings.TrimSuffix(s, "\x00")) {
+			if err := writeReplayPacket(rw, packet); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+var (
+	trIDRegexp    = regexp.MustCompile(`transaction_id="\d*"`)
+	trIDArgRegexp = regexp.MustCompile(` -i \d+`)
+)
+
+// answer returns the packets answering the command.
+func (r *Replayer) answer(cmd simCommand, line string) []string {
+	args := trIDArgRegexp.ReplaceAllString(line, "")
+
+	send := -1
+	for i, e := range r.entries {
+		if e.Dir != "send" || r.answered[i] || e.Command != cmd.name {
+			continue
+		}
+		if send < 0 {
+			send = i
+		}
+		if trIDArgRegexp.ReplaceAllString(e.Packet, "") == args {
+			send = i
+			break
+		}
+	}
+
+	errorResponse := func(msg string) []string {
+		log.Println("replay:", cmd.name, msg)
+		return []string{fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<response xmlns="urn:debugger_protocol_v1" command="%s" transaction_id="%s"><error code="4"><message>%s</message></error></response>`,
+			xmlAttr(cmd.name), xmlAttr(cmd.id), cdata("replay: "+msg))}
+	}
+	if send < 0 {
+		return errorResponse("command not in the transcript")
+	}
+	r.answered[send] = true
+
+	resp := -1
+	for i := send + 1; i < len(r.entries); i++ {
+		e := r.entries[i]
+		if e.Dir == "recv" && e.Type == "response" && e.TrID == r.entries[send].TrID {
+			resp = i
+			break
+		}
+	}
+	if resp < 0 {
+		return errorResponse("no recorded response")
+	}
+
+	var packets []string
+	for ; r.sent < resp; r.sent++ {
+		if e := r.entries[r.sent]; e.Dir == "recv" && (e.Type == "stream" || e.Type == "notify") {
+			packets = append(packets, e.Packet)
+		}
+	}
+	packet := trIDRegexp.ReplaceAllString(r.entries[resp].Packet, `transaction_id="`+xmlAttr(cmd.id)+`"`)
+	return append(packets, packet)
+}
+
+func writeReplayPacket(w io.Writer, packet string) error {
+	if _, err := fmt.Fprintf(w, "%d\x00%s\x00", len(packet), packet); err != nil {
+		return fmt.Errorf("replay write. error: %w", err)
+	}
+	return nil
+}
+
+// replayCmd replays the session of the transcript file, the first one
+// without a session number.
+func (xc *Client) replayCmd(args []string) error {
+	if xc.started {
+		return fmt.Errorf("phpdebug already started")
+	}
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("usage: php replay FILE [SESSION]")
+	}
+
+	entries, err := ReadTranscript(args[0])
+	if err != nil {
+		return err
+	}
+	session := 0
+	if len(args) == 2 {
+		if session, err = strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("invalid session %q", args[1])
+		}
+	}
+	r, err := NewReplayer(entries, session)
+	if err != nil {
+		return err
+	}
+
+	if err := xc.serveEngine(r.Dial); err != nil {
+		return err
+	}
+	xc.started = true
+	xc.Editor.Message("replaying ", filepath.Base(args[0]), ". Run the recorded commands")
+	return nil
+}
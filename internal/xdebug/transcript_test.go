@@ -0,0 +1,112 @@
+package xdebug
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runSession steps over the first line of the demo session and continues
+// to the end. It returns the output.
+func runSession(t *testing.T, xc *Client, ed *testEditor, index string) string {
+	at := func(line int) func() bool {
+		return func() bool {
+			uri, l := xc.Location()
+			stack := xc.Stack()
+			return uri == pathToURI(index) && l == line && len(stack) > 0 && stack[0].Line == line && len(xc.Contexts()) > 0
+		}
+	}
+	runUntil(t, at(2))
+	assert.NoError(t, xc.ProcessCommand([]string{"n"}))
+	runUntil(t, at(4))
+	assert.NoError(t, xc.ProcessCommand([]string{"c"}))
+	runUntil(t, func() bool { return xc.Status() == "" })
+
+	drainJobs()
+	return ed.output[1]
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xdebug")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	p, err := DemoProgram(dir)
+	assert.NoError(t, err)
+	index := filepath.Join(dir, "index.php")
+	transcript := filepath.Join(dir, "logs", "dbgp.jsonl")
+
+	ed := &testEditor{}
+	xc := &Client{Editor: ed, Config: Config{BreakFirst: true, Record: transcript}}
+	assert.NoError(t, xc.Demo(p))
+	xc.started = true
+	output := runSession(t, xc, ed, index)
+	assert.Equal(t, "Hello, Ada!\nHello, Grace!\nHello, Linus!\ngreeted 3 people\n", output)
+	assert.Nil(t, xc.recorder)
+
+	entries, err := ReadTranscript(transcript)
+	assert.NoError(t, err)
+	assert.Equal(t, "recv", entries[0].Dir)
+	assert.Equal(t, "init", entries[0].Type)
+	assert.Contains(t, entries[0].Packet, `idekey="micro-demo"`)
+	var step, resp, streams int
+	for _, e := range entries {
+		assert.Equal(t, 1, e.Session)
+		assert.False(t, e.Time.IsZero())
+		switch {
+		case e.Dir == "send" && e.Command == "step_over":
+			step = e.TrID
+			assert.Equal(t, "step_over -i 13", e.Packet)
+		case e.Dir == "recv" && e.Command == "step_over":
+			resp = e.TrID
+			assert.True(t, strings.HasPrefix(e.Packet, "<?xml"))
+		case e.Type == "stream":
+			streams++
+		}
+	}
+	assert.NotZero(t, step)
+	assert.Equal(t, step, resp)
+	assert.Equal(t, 4, streams)
+
+	// the replay reproduces the session
+	ed2 := &testEditor{}
+	xc2 := &Client{Editor: ed2, Config: Config{BreakFirst: true}}
+	assert.NoError(t, xc2.ProcessCommand([]string{"replay", transcript}))
+	output = runSession(t, xc2, ed2, index)
+	assert.Equal(t, "Hello, Ada!\nHello, Grace!\nHello, Linus!\ngreeted 3 people\n", output)
+	assert.Empty(t, ed2.errors)
+}
+
+func TestReplayAnswer(t *testing.T) {
+	entries := []TranscriptEntry{
+		{Session: 2, Dir: "recv", Type: "init", Packet: "<init/>"},
+		{Session: 2, Dir: "send", Command: "eval", TrID: 3, Packet: "eval -i 3 -- YQ=="},
+		{Session: 2, Dir: "send", Command: "eval", TrID: 4, Packet: "eval -i 4 -- Yg=="},
+		{Session: 2, Dir: "recv", Type: "stream", Packet: "<stream/>"},
+		{Session: 2, Dir: "recv", Type: "response", Command: "eval", TrID: 4, Packet: `<response command="eval" transaction_id="4">b</response>`},
+		{Session: 2, Dir: "recv", Type: "response", Command: "eval", TrID: 3, Packet: `<response command="eval" transaction_id="3">a</response>`},
+	}
+	_, err := NewReplayer(entries, 1)
+	assert.EqualError(t, err, "no recorded session 1")
+	r, err := NewReplayer(entries, 0)
+	assert.NoError(t, err)
+	r.sent = 1
+
+	// the command with the same arguments is answered
+	cmd, err := parseSimCommand("eval -i 9 -- Yg==")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"<stream/>", `<response command="eval" transaction_id="9">b</response>`},
+		r.answer(cmd, "eval -i 9 -- Yg=="))
+
+	cmd, err = parseSimCommand("eval -i 10 -- Yw==")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`<response command="eval" transaction_id="10">a</response>`},
+		r.answer(cmd, "eval -i 10 -- Yw=="))
+
+	packets := r.answer(cmd, "eval -i 10 -- Yw==")
+	assert.Len(t, packets, 1)
+	assert.Contains(t, packets[0], "replay: command not in the transcript")
+}
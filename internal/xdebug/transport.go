@@ -0,0 +1,186 @@
+package xdebug
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/zyedidia/micro/v2/internal/shell"
+)
+
+// handler is called on the main loop with the response to a command.
+type handler func(Response)
+
+// transport owns the DBGp connection. Commands are written from the main loop,
+// while a reader goroutine frames incoming packets, matches responses to the
+// transaction ID of the command and posts them back to the main loop.
+type transport struct {
+	conn net.Conn
+	wmu  sync.Mutex // orders the writes of commands, held without mu
+
+	mu      sync.Mutex
+	transID int
+	pending map[int]handler
+	closed  bool
+
+	onNotify func(Response)
+	onStream func(Response)
+	onClose  func(error)
+	record   func(TranscriptEntry) // records packets to the transcript, can be nil
+}
+
+func newTransport(conn net.Conn) *transport {
+	return &transport{
+		conn:    conn,
+		transID: 1,
+		pending: make(map[int]handler),
+	}
+}
+
+func (t *transport) start() {
+	go t.readLoop()
+}
+
+// send writes the command with the next transaction ID. args are appended
+// after the ID and data, if not nil, is sent base64 encoded after "--".
+// h is called with the response, it can be nil.
+func (t *transport) send(cmd string, args string, data []byte, h handler) (int, error) {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return 0, fmt.Errorf("%s: connection closed", cmd)
+	}
+	id := t.transID
+	t.transID++
+	t.pending[id] = h
+	t.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s -i %d", cmd, id)
+	if args != "" {
+		b.WriteString(" " + args)
+	}
+	if data != nil {
+		b.WriteString(" -- " + base64.StdEncoding.EncodeToString(data))
+	}
+	b.WriteByte(0)
+
+	if t.record != nil {
+		t.record(TranscriptEntry{Dir: "send", Command: cmd, TrID: id, Packet: strings.TrimSuffix(b.String(), "\x00")})
+	}
+	log.Println("send:", b.String())
+	// the reader goroutine is not blocked while the engine is slow to read
+	if _, err := t.conn.Write([]byte(b.String())); err != nil {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+		return 0, fmt.Errorf("%s write. error: %w", cmd, err)
+	}
+
+	return id, nil
+}
+
+// quoteArg quotes the command argument value which can contain spaces and quotes.
+func quoteArg(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return `"` + s + `"`
+}
+
+func (t *transport) readLoop() {
+	var err error
+
+	for {
+		var b []byte
+		b, err = readBlock(t.conn)
+		if err != nil {
+			break
+		}
+
+		var resp Response
+		resp, err = unmarshalCommand(b)
+		if t.record != nil {
+			t.record(TranscriptEntry{
+				Dir:     "recv",
+				Type:    resp.XMLName.Local,
+				Command: resp.Command,
+				TrID:    resp.TrID,
+				Packet:  string(bytes.TrimRight(b, "\x00")),
+			})
+		}
+		if err != nil {
+			break
+		}
+
+		t.dispatch(resp, b)
+	}
+
+	t.mu.Lock()
+	wasClosed := t.closed
+	t.closed = true
+	t.pending = make(map[int]handler)
+	t.mu.Unlock()
+
+	if wasClosed {
+		err = nil
+	}
+	log.Println("xdebug connection closed:", err)
+
+	if t.onClose != nil {
+		shell.Post(func() { t.onClose(err) })
+	}
+}
+
+func (t *transport) dispatch(resp Response, b []byte) {
+	switch resp.XMLName.Local {
+	case "notify":
+		log.Println("notify:", string(b))
+		if t.onNotify != nil {
+			shell.Post(func() { t.onNotify(resp) })
+		}
+		return
+	case "stream":
+		if t.onStream != nil {
+			shell.Post(func() { t.onStream(resp) })
+		}
+		return
+	}
+
+	if resp.Command != "source" && resp.Command != "stack_get" && resp.Command != "eval" {
+		log.Println("block:", string(b))
+	}
+
+	t.mu.Lock()
+	h, ok := t.pending[resp.TrID]
+	delete(t.pending, resp.TrID)
+	t.mu.Unlock()
+
+	if !ok {
+		log.Println("unexpected response:", resp.Command, resp.TrID)
+		return
+	}
+
+	if h != nil {
+		shell.Post(func() { h(resp) })
+	}
+}
+
+// close closes the connection. The reader goroutine exits and calls onClose.
+func (t *transport) close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.mu.Unlock()
+
+	return t.conn.Close()
+}
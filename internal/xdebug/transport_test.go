@@ -0,0 +1,112 @@
+package xdebug
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/shell"
+)
+
+func writePacket(t *testing.T, conn net.Conn, xml string) {
+	_, err := fmt.Fprintf(conn, "%d\x00%s\x00", len(xml), xml)
+	assert.NoError(t, err)
+}
+
+func readCommand(t *testing.T, conn net.Conn) string {
+	var b bytes.Buffer
+	c := make([]byte, 1)
+	for {
+		_, err := conn.Read(c)
+		assert.NoError(t, err)
+		if c[0] == 0 {
+			return b.String()
+		}
+		b.WriteByte(c[0])
+	}
+}
+
+// engineCommands reads commands from the engine end of the pipe in the
+// background until it is closed, so that handlers sending several commands
+// do not block on the pipe.
+func engineCommands(conn net.Conn) <-chan string {
+	c := make(chan string, 100)
+	go func() {
+		defer close(c)
+		r := bufio.NewReader(conn)
+		for {
+			s, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			c <- strings.TrimSuffix(s, "\x00")
+		}
+	}()
+	return c
+}
+
+// nextCommand returns the next command read by engineCommands.
+func nextCommand(t *testing.T, c <-chan string) string {
+	select {
+	case s := <-c:
+		return s
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for command")
+	}
+	return ""
+}
+
+// runJob runs the next callback posted to the main loop.
+func runJob(t *testing.T) {
+	select {
+	case f := <-shell.Jobs:
+		f.Function(f.Output, f.Args)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for posted callback")
+	}
+}
+
+func TestTransportCorrelation(t *testing.T) {
+	ide, engine := net.Pipe()
+	tr := newTransport(ide)
+
+	var notified, closed bool
+	tr.onNotify = func(resp Response) { notified = resp.Name == "breakpoint_resolved" }
+	tr.onClose = func(err error) { closed = true }
+	tr.start()
+
+	var first, second Response
+	go func() {
+		_, err := tr.send("stack_get", "", nil, func(resp Response) { first = resp })
+		assert.NoError(t, err)
+		_, err = tr.send("eval", "", []byte("1+1"), func(resp Response) { second = resp })
+		assert.NoError(t, err)
+	}()
+
+	assert.Equal(t, "stack_get -i 1", readCommand(t, engine))
+	assert.Equal(t, "eval -i 2 -- MSsx", readCommand(t, engine))
+
+	// answer out of order with a notification in between
+	writePacket(t, engine, `<response command="eval" transaction_id="2"/>`)
+	writePacket(t, engine, `<notify name="breakpoint_resolved"/>`)
+	writePacket(t, engine, `<response command="stack_get" transaction_id="1"/>`)
+	engine.Close()
+
+	runJob(t)
+	runJob(t)
+	runJob(t)
+	runJob(t)
+
+	assert.Equal(t, "stack_get", first.Command)
+	assert.Equal(t, "eval", second.Command)
+	assert.True(t, notified)
+	assert.True(t, closed)
+
+	_, err := tr.send("run", "", nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,237 @@
+package xdebug
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// Contexts returns the variable contexts of the current stack frame.
+func (xc *Client) Contexts() []*debugger.Context {
+	if xc.current == nil {
+		return nil
+	}
+	return xc.current.contexts
+}
+
+func newProperty(p property, context, depth int) *debugger.Property {
+	v := p.Text
+	if p.Encoding == "base64" {
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			v = "error:" + err.Error()
+		} else {
+			v = string(b)
+		}
+	}
+
+	prop := &debugger.Property{
+		Name:        p.Name,
+		FullName:    p.FullName,
+		Type:        p.Type,
+		ClassName:   p.ClassName,
+		Value:       v,
+		NumChildren: p.NumChildren,
+		Context:     context,
+		Depth:       depth,
+	}
+	if prop.FullName == "" {
+		prop.FullName = prop.Name
+	}
+	if len(p.Properties) > 0 {
+		prop.Pages = p.Page + 1
+		prop.Children = newProperties(p.Properties, context, depth)
+	}
+
+	return prop
+}
+
+func newProperties(props []property, context, depth int) []*debugger.Property {
+	var res []*debugger.Property
+	for _, p := range props {
+		res = append(res, newProperty(p, context, depth))
+	}
+	return res
+}
+
+// refreshVariables fetches contexts of the current stack frame. Contexts and
+// properties which were expanded stay expanded.
+func (s *session) refreshVariables() {
+	if len(s.contexts) > 0 {
+		s.getContexts()
+		return
+	}
+
+	err := s.command("context_names", fmt.Sprintf("-d %d", s.depth), nil, func(resp Response) {
+		s.contexts = nil
+		for _, c := range resp.Contexts {
+			s.contexts = append(s.contexts, &debugger.Context{
+				ID:   c.ID,
+				Name: c.Name,
+				// Locals are expanded by default
+				Expanded: c.ID == 0,
+			})
+		}
+		s.getContexts()
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (s *session) getContexts() {
+	for _, c := range s.contexts {
+		if c.Expanded {
+			s.getContext(c)
+		}
+	}
+}
+
+func (s *session) getContext(c *debugger.Context) {
+	depth := s.depth
+	args := fmt.Sprintf("-c %d -d %d", c.ID, depth)
+	err := s.command("context_get", args, nil, func(resp Response) {
+		expanded := make(map[string]bool)
+		collectExpanded(c.Properties, expanded)
+
+		c.Properties = newProperties(resp.Properties, c.ID, depth)
+		s.restoreExpanded(c.Properties, expanded)
+		s.xc.Editor.VariablesChanged()
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func collectExpanded(props []*debugger.Property, expanded map[string]bool) {
+	for _, p := range props {
+		if p.Expanded {
+			expanded[p.FullName] = true
+			collectExpanded(p.Children, expanded)
+		}
+	}
+}
+
+func (s *session) restoreExpanded(props []*debugger.Property, expanded map[string]bool) {
+	for _, p := range props {
+		if !expanded[p.FullName] {
+			continue
+		}
+		p.Expanded = true
+		if len(p.Children) == 0 && p.NumChildren > 0 {
+			s.loadChildren(p, func() { s.restoreExpanded(p.Children, expanded) })
+		} else {
+			s.restoreExpanded(p.Children, expanded)
+		}
+	}
+}
+
+// loadChildren fetches the next page of children of the property.
+func (s *session) loadChildren(p *debugger.Property, done func()) {
+	page := p.Pages
+	args := fmt.Sprintf("-n %s -c %d -d %d -p %d", quoteArg(p.FullName), p.Context, p.Depth, page)
+	err := s.command("property_get", args, nil, func(resp Response) {
+		if len(resp.Properties) == 0 {
+			return
+		}
+		p.Pages = page + 1
+		for _, c := range resp.Properties[0].Properties {
+			p.Children = append(p.Children, newProperty(c, p.Context, p.Depth))
+		}
+		if done != nil {
+			done()
+		}
+		s.xc.Editor.VariablesChanged()
+	})
+	if err != nil {
+		log.Println(err)
+		s.xc.Editor.Error(err)
+	}
+}
+
+// ToggleContext expands or collapses the context. Expanded context is
+// fetched from the engine.
+func (xc *Client) ToggleContext(c *debugger.Context) {
+	c.Expanded = !c.Expanded
+	if c.Expanded && xc.current != nil {
+		xc.current.getContext(c)
+	}
+	xc.Editor.VariablesChanged()
+}
+
+// ToggleProperty expands or collapses the property. Children are fetched
+// from the engine when the property is expanded first time.
+func (xc *Client) ToggleProperty(p *debugger.Property) {
+	p.Expanded = !p.Expanded
+	if p.Expanded && len(p.Children) == 0 && p.NumChildren > 0 && xc.current != nil {
+		xc.current.loadChildren(p, nil)
+	}
+	xc.Editor.VariablesChanged()
+}
+
+// LoadMore fetches the next page of children of the property.
+func (xc *Client) LoadMore(p *debugger.Property) {
+	if p.HasMore() && xc.current != nil {
+		xc.current.loadChildren(p, nil)
+	}
+}
+
+// Eval evaluates the expression in the selected stack frame of the current
+// session and calls done with the result. Engines evaluate code in the top
+// frame only, so in other frames the expression must be a variable path
+// like $a['b']->c, which is fetched with property_get. Other expressions are
+// rejected there.
+func (xc *Client) Eval(expr string, done func(*debugger.Property, error)) error {
+	if s := xc.current; s != nil && s.depth > 0 && !variableRegexp.MatchString(expr) {
+		return fmt.Errorf("eval: expressions can only be evaluated in the top frame, %q is not a variable", expr)
+	}
+	return xc.evaluate(expr, false, done)
+}
+
+// variableRegexp matches variable paths like $a, $a['b'] or $this->c::$d
+var variableRegexp = regexp.MustCompile(`^\$\w+((->|::)\$?\w+|\[[^\]]*\])*$`)
+
+// Inspect is like Eval but fetches variable paths with property_get in all
+// frames, so that inspecting a variable does not run any code of the
+// script like magic getters. Other expressions are not evaluated, Inspect
+// returns debugger.ErrNotVariable.
+func (xc *Client) Inspect(expr string, done func(*debugger.Property, error)) error {
+	if !variableRegexp.MatchString(expr) {
+		return debugger.ErrNotVariable
+	}
+	return xc.evaluate(expr, true, done)
+}
+
+func (xc *Client) evaluate(expr string, property bool, done func(*debugger.Property, error)) error {
+	s := xc.current
+	if s == nil || s.status != "break" {
+		return fmt.Errorf("eval: the debugger is not stopped at a break")
+	}
+
+	cmd, args, data := "eval", "", []byte(expr)
+	if property || s.depth > 0 {
+		cmd, args, data = "property_get", fmt.Sprintf("-n %s -d %d", quoteArg(expr), s.depth), nil
+	}
+
+	depth := s.depth
+	_, err := s.conn.send(cmd, args, data, func(resp Response) {
+		if resp.Error.Code != 0 {
+			done(nil, fmt.Errorf("error %d: %s", resp.Error.Code, resp.Error.Message.Text))
+			return
+		}
+		if len(resp.Properties) == 0 {
+			done(nil, fmt.Errorf("no result"))
+			return
+		}
+		p := newProperty(resp.Properties[0], 0, depth)
+		// eval results have no name, use the expression to fetch children
+		if p.FullName == "" {
+			p.FullName = expr
+		}
+		done(p, nil)
+	})
+	return err
+}
@@ -0,0 +1,46 @@
+package xdebug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+func TestEval(t *testing.T) {
+	xc, s, engine := testSession(t)
+	defer engine.Close()
+
+	var value *debugger.Property
+	var evalErr error
+	done := func(p *debugger.Property, err error) { value, evalErr = p, err }
+
+	go func() { assert.NoError(t, xc.Eval("count($a)", done)) }()
+	assert.Equal(t, "eval -i 1 -- Y291bnQoJGEp", readCommand(t, engine))
+	writePacket(t, engine, `<response command="eval" transaction_id="1"><property type="int"><![CDATA[3]]></property></response>`)
+	runJob(t)
+	assert.NoError(t, evalErr)
+	assert.Equal(t, "int 3", value.Summary())
+	assert.Equal(t, "count($a)", value.FullName)
+
+	// outer frames are read with property_get
+	s.depth = 2
+	go func() { assert.NoError(t, xc.Eval("$a['k']", done)) }()
+	assert.Equal(t, `property_get -i 2 -n "$a['k']" -d 2`, readCommand(t, engine))
+	writePacket(t, engine, `<response command="property_get" transaction_id="2"><error code="300"><message><![CDATA[can not get property]]></message></error></response>`)
+	runJob(t)
+	assert.EqualError(t, evalErr, "error 300: can not get property")
+	// other expressions would be evaluated in the top frame
+	assert.EqualError(t, xc.Eval("count($a)", done), `eval: expressions can only be evaluated in the top frame, "count($a)" is not a variable`)
+
+	// variables are read without running code
+	s.depth = 0
+	go func() { assert.NoError(t, xc.Inspect("$this->items", done)) }()
+	assert.Equal(t, `property_get -i 3 -n "$this->items" -d 0`, readCommand(t, engine))
+	// other expressions are not evaluated, they can run code like exit
+	assert.Equal(t, debugger.ErrNotVariable, xc.Inspect("exit", done))
+	assert.Equal(t, debugger.ErrNotVariable, xc.Inspect("Foo::bar", done))
+
+	s.status = "running"
+	assert.Error(t, xc.Eval("1", done))
+}
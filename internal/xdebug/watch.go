@@ -0,0 +1,80 @@
+package xdebug
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/zyedidia/micro/v2/internal/debugger"
+)
+
+// Watches returns the watch expressions.
+func (xc *Client) Watches() []*debugger.Watch {
+	return xc.watches
+}
+
+// SetWatches replaces watch expressions.
+func (xc *Client) SetWatches(exprs []string) {
+	xc.watches = nil
+	for _, e := range exprs {
+		xc.watches = append(xc.watches, &debugger.Watch{Expression: e})
+	}
+}
+
+// WatchExpressions returns the watch expressions to be saved.
+func (xc *Client) WatchExpressions() []string {
+	var exprs []string
+	for _, w := range xc.watches {
+		exprs = append(exprs, w.Expression)
+	}
+	return exprs
+}
+
+// AddWatch adds the watch expression and evaluates it if the session is active.
+func (xc *Client) AddWatch(expr string) {
+	w := &debugger.Watch{Expression: expr}
+	xc.watches = append(xc.watches, w)
+	if xc.current != nil && xc.current.status == "break" {
+		xc.current.evalWatch(w)
+	}
+	xc.Editor.WatchesChanged()
+}
+
+// RemoveWatch removes the n-th watch expression.
+func (xc *Client) RemoveWatch(n int) error {
+	if n < 1 || n > len(xc.watches) {
+		return fmt.Errorf("no watch %d", n)
+	}
+	xc.watches = append(xc.watches[:n-1], xc.watches[n:]...)
+	xc.Editor.WatchesChanged()
+	return nil
+}
+
+// evalWatches evaluates all watch expressions in the current stack frame.
+func (s *session) evalWatches() {
+	for _, w := range s.xc.watches {
+		s.evalWatch(w)
+	}
+}
+
+func (s *session) evalWatch(w *debugger.Watch) {
+	_, err := s.conn.send("eval", "", []byte(w.Expression), func(resp Response) {
+		prev := w.Value
+
+		w.Value, w.Error = nil, ""
+		if resp.Error.Code != 0 {
+			w.Error = resp.Error.Message.Text
+		} else if len(resp.Properties) > 0 {
+			w.Value = newProperty(resp.Properties[0], 0, s.depth)
+			// eval results have no name, use the expression to fetch children
+			if w.Value.FullName == "" {
+				w.Value.FullName = w.Expression
+			}
+		}
+
+		w.Changed = prev != nil && !debugger.SameValue(prev, w.Value)
+		s.xc.Editor.WatchesChanged()
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,33 @@
+package xdebug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchChanged(t *testing.T) {
+	xc, s, engine := testSession(t)
+	defer engine.Close()
+	cmds := engineCommands(engine)
+	xc.SetWatches([]string{"$a"})
+	w := xc.Watches()[0]
+
+	eval := func(id, value string) {
+		s.evalWatches()
+		nextCommand(t, cmds)
+		writePacket(t, engine, `<response command="eval" transaction_id="`+id+`"><property type="array" numchildren="2" children="1">`+value+`</property></response>`)
+		runJob(t)
+	}
+
+	eval("1", `<property name="0" type="int"><![CDATA[1]]></property><property name="1" type="int"><![CDATA[2]]></property>`)
+	assert.False(t, w.Changed)
+
+	// the same number of elements with another value
+	eval("2", `<property name="0" type="int"><![CDATA[1]]></property><property name="1" type="int"><![CDATA[3]]></property>`)
+	assert.Equal(t, "array(2)", w.Value.Summary())
+	assert.True(t, w.Changed)
+
+	eval("3", `<property name="0" type="int"><![CDATA[1]]></property><property name="1" type="int"><![CDATA[3]]></property>`)
+	assert.False(t, w.Changed)
+}